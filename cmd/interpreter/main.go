@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/ozansz/semantix/internal/interpreter"
@@ -33,22 +34,35 @@ func main() {
 	}
 	interpreter := interpreter.New(parser, store, intOps...)
 
+	// The store buffers its changes, so it is closed on every way out to
+	// flush them, but only once.
+	var closeOnce sync.Once
+	closeStore := func() {
+		closeOnce.Do(func() {
+			if err := store.Close(); err != nil {
+				log.Printf("Error closing store: %v", err)
+			}
+		})
+	}
+
 	c := make(chan os.Signal)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		fmt.Printf("Received SIGTERM, exiting...")
 		interpreter.Quit()
-		store.Close()
+		closeStore()
 		os.Exit(1)
 	}()
 
 	if *sxQLFile != "" {
 		file, err := parser.ParseFile(*sxQLFile)
 		if err != nil {
+			closeStore()
 			log.Fatalf("Error parsing file: %v", err)
 		}
 		interpreter.ExecuteBatch(file.Expressions)
 	}
 	interpreter.ExecuteREPL()
+	closeStore()
 }
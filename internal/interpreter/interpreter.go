@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/ozansz/semantix/internal/parser"
@@ -34,8 +35,11 @@ type Interpreter struct {
 	parser *parser.Parser
 	prompt string
 	store  store.Store
-	quit   chan struct{}
-	debug  bool
+	// graph is the graph facts are added to and queries read, every graph
+	// for the default one.
+	graph string
+	quit  chan struct{}
+	debug bool
 }
 
 type InterpreterOption func(*Interpreter)
@@ -47,11 +51,12 @@ func WithDebug() func(*Interpreter) {
 }
 
 // New returns a new interpreter.
-func New(parser *parser.Parser, store store.Store, opts ...InterpreterOption) *Interpreter {
+func New(parser *parser.Parser, s store.Store, opts ...InterpreterOption) *Interpreter {
 	i := &Interpreter{
 		parser: parser,
-		store:  store,
+		store:  s,
 		prompt: defaultPrompt,
+		graph:  store.DefaultGraph,
 		quit:   make(chan struct{}),
 	}
 	for _, o := range opts {
@@ -73,14 +78,51 @@ func (i *Interpreter) ExecuteBatch(exprs []*parser.Expression) {
 
 // Execute executes the given expression.
 func (i *Interpreter) Execute(expr *parser.Expression) {
-	if expr.Query != nil {
-		if err := i.executeQuery(expr.Query); err != nil {
+	if err := parser.ResolveFactIDs(expr, i.store.GetByID); err != nil {
+		fmt.Printf("!! %v\n", err)
+	} else if expr.Rule != nil {
+		if err := i.executeRule(expr.Rule); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Construct != nil {
+		if err := i.executeConstruct(expr.Construct); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Update != nil {
+		if err := i.executeUpdate(expr.Update); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Describe != nil {
+		if err := i.executeDescribe(expr.Describe); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Use != nil {
+		i.graph = expr.Use.Graph
+		fmt.Printf("Using graph %s\n", i.graph)
+	} else if expr.Drop != nil {
+		if err := i.store.DropGraph(expr.Drop.Graph); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Reload != nil {
+		if err := i.executeReload(expr.Reload); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Query != nil {
+		if err := i.executeQuery(expr.Query, expr.Modifiers); err != nil {
 			fmt.Printf("!! %v\n", err)
 		}
 	} else if expr.Fact != nil {
 		if err := i.executeFact(expr.Fact); err != nil {
 			fmt.Printf("!! %v\n", err)
 		}
+	} else if expr.Anchor != nil {
+		if err := i.executeFact(expr.Anchor.Fact); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
+	} else if expr.Delete != nil {
+		if err := i.executeDelete(expr.Delete); err != nil {
+			fmt.Printf("!! %v\n", err)
+		}
 	}
 }
 
@@ -119,23 +161,237 @@ func (i *Interpreter) ExecuteREPL() {
 }
 
 func (i *Interpreter) executeFact(f *parser.Fact) error {
-	return i.store.Add(f)
+	return i.add([]*parser.Fact{f})
+}
+
+// add adds the facts to the graph in use.
+func (i *Interpreter) add(facts []*parser.Fact) error {
+	if i.graph == store.DefaultGraph {
+		return i.store.AddAll(facts)
+	}
+	return i.store.AddToGraph(i.graph, facts)
+}
+
+// reader returns the reader of the facts of the graph in use, or of every
+// graph for the default one.
+func (i *Interpreter) reader() store.Reader {
+	if i.graph == store.DefaultGraph {
+		return i.store
+	}
+	return i.store.InGraphs([]string{i.graph})
+}
+
+// asserted returns the reader of the asserted facts of the graph in use, or
+// of every graph for the default one. Deletes and updates match against it,
+// as the facts derived by rules cannot be changed.
+func (i *Interpreter) asserted() store.Reader {
+	if i.graph == store.DefaultGraph {
+		return i.store.Asserted()
+	}
+	return i.store.InGraphs([]string{i.graph})
+}
+
+// executeReload replaces the facts of the graph with the facts of the file.
+// The facts of the file can reference the ones before them by ID.
+func (i *Interpreter) executeReload(r *parser.Reload) error {
+	file, err := i.parser.ParseFile(r.Path)
+	if err != nil {
+		return err
+	}
+	loaded := map[uint32]*parser.Fact{}
+	lookup := func(id uint32) (*parser.Fact, error) {
+		if f, ok := loaded[id]; ok {
+			return f.Copy(), nil
+		}
+		return i.store.GetByID(id)
+	}
+	facts := []*parser.Fact{}
+	for _, e := range file.Expressions {
+		if err := parser.ResolveFactIDs(e, lookup); err != nil {
+			return err
+		}
+		var f *parser.Fact
+		switch {
+		case e.Fact != nil:
+			f = e.Fact
+		case e.Anchor != nil:
+			f = e.Anchor.Fact
+		default:
+			continue
+		}
+		facts = append(facts, f)
+		loaded[store.FactHash(f)] = f
+	}
+	if err := i.store.ReloadGraph(r.Graph, facts); err != nil {
+		return err
+	}
+	fmt.Printf("Reloaded %d fact(s) into graph %s\n", len(facts), r.Graph)
+	return nil
+}
+
+func (i *Interpreter) executeRule(r *parser.Rule) error {
+	rr, err := store.RuleFromAST(r)
+	if err != nil {
+		return err
+	}
+	return i.store.AddRule(rr)
+}
+
+// executeConstruct prints the facts instantiated by the construct, or adds
+// them to the store at once for inserts.
+func (i *Interpreter) executeConstruct(c *parser.Construct) error {
+	cc, err := store.ConstructFromAST(c)
+	if err != nil {
+		return err
+	}
+
+	if i.debug {
+		fmt.Printf("Executing construct: %s\n", i.parser.Compact(cc.Pretty()))
+	}
+
+	facts, err := cc.Facts(i.reader())
+	if err != nil {
+		return err
+	}
+	if cc.Insert {
+		if err := i.add(facts); err != nil {
+			return err
+		}
+		fmt.Printf("Inserted %d fact(s)\n", len(facts))
+		return nil
+	}
+	fmt.Println()
+	for _, f := range facts {
+		fmt.Println(i.parser.Compact(f.Pretty()))
+	}
+	fmt.Println()
+	return nil
+}
+
+// executeUpdate rewrites the facts of the graph in use matched by the update,
+// along with the facts nesting them.
+func (i *Interpreter) executeUpdate(u *parser.Update) error {
+	uu, err := store.UpdateFromAST(u)
+	if err != nil {
+		return err
+	}
+
+	if i.debug {
+		fmt.Printf("Executing update: %s\n", i.parser.Compact(uu.Pretty()))
+	}
+
+	rewrites, err := uu.Rewrites(i.asserted())
+	if err != nil {
+		return err
+	}
+	if err := store.ApplyRewrites(i.store, rewrites); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %d fact(s)\n", len(rewrites))
+	return nil
+}
+
+// executeDescribe prints the facts around the subject, ordered by their IDs.
+func (i *Interpreter) executeDescribe(d *parser.Describe) error {
+	dd, err := store.DescribeFromAST(d)
+	if err != nil {
+		return err
+	}
+
+	if i.debug {
+		fmt.Printf("Executing describe: %s\n", i.parser.Compact(dd.Pretty()))
+	}
+
+	facts, err := dd.Facts(i.reader())
+	if err != nil {
+		return err
+	}
+	ids := make([]uint32, 0, len(facts))
+	for id := range facts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	fmt.Println()
+	for _, id := range ids {
+		fmt.Printf("%010d: %s\n", id, i.parser.Compact(facts[id].Pretty()))
+	}
+	fmt.Println()
+	return nil
 }
 
-func (i *Interpreter) executeQuery(q *parser.Query) error {
-	qq := store.QueryFromAST(q)
+// executeDelete retracts every fact matched by the query. For linked queries,
+// the facts matched by all patterns of the chain are retracted. The facts are
+// collected before retracting any of them, so that a failing query changes
+// nothing.
+func (i *Interpreter) executeDelete(q *parser.Query) error {
+	qq, err := store.QueryFromAST(q)
+	if err != nil {
+		return err
+	}
 
 	if i.debug {
-		fmt.Printf("Executing query: %s\n", qq.Pretty())
+		fmt.Printf("Executing delete: %s\n", i.parser.Compact(qq.Pretty()))
+	}
+
+	res, err := store.Evaluate(i.asserted(), qq)
+	if err != nil {
+		return err
+	}
+	seen := map[uint32]bool{}
+	facts := []*parser.Fact{}
+	for _, row := range res.Rows {
+		for j, id := range row.FactIDs {
+			if !seen[id] {
+				seen[id] = true
+				facts = append(facts, row.Facts[j])
+			}
+		}
 	}
+	for _, f := range facts {
+		if err := i.store.Delete(f); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Deleted %d fact(s)\n", len(facts))
+	return nil
+}
 
-	results, err := i.store.Get(qq)
+func (i *Interpreter) executeQuery(q *parser.Query, m *parser.Modifiers) error {
+	qq, err := store.QueryFromAST(q)
+	if err != nil {
+		return err
+	}
+	if qq.Modifiers, err = store.ModifiersFromAST(m); err != nil {
+		return err
+	}
+
+	if i.debug {
+		fmt.Printf("Executing query: %s\n", i.parser.Compact(qq.Pretty()))
+	}
+
+	res, err := store.Evaluate(i.reader(), qq)
 	if err != nil {
 		return err
 	}
 	fmt.Println()
-	for id, result := range results {
-		fmt.Printf("%010d: %s\n", id, result.Pretty())
+	for _, row := range res.Rows {
+		ids := make([]string, 0, len(row.FactIDs))
+		for _, id := range row.FactIDs {
+			ids = append(ids, fmt.Sprintf("%010d", id))
+		}
+		if len(ids) == 0 {
+			fmt.Println(i.parser.Compact(row.Pretty(res.Variables)))
+			continue
+		}
+		if len(res.Variables) > 0 {
+			fmt.Printf("%s: %s\n", strings.Join(ids, " -> "), i.parser.Compact(row.Pretty(res.Variables)))
+			continue
+		}
+		facts := make([]string, 0, len(row.Facts))
+		for _, f := range row.Facts {
+			facts = append(facts, i.parser.Compact(f.Pretty()))
+		}
+		fmt.Printf("%s: %s\n", strings.Join(ids, " -> "), strings.Join(facts, " -> "))
 	}
 	fmt.Println()
 	return nil
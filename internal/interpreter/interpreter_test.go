@@ -0,0 +1,193 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+	"github.com/ozansz/semantix/internal/store/filestore"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		desc  string
+		lines []string
+		// want is the asserted facts of the store after the lines.
+		want []string
+		// wantErr is set if the last line fails.
+		wantErr bool
+	}{
+		{
+			desc: "delete with a rule",
+			lines: []string{
+				"(Ozan, parentOf, Ali)",
+				"(Ali, parentOf, Ayse)",
+				"(?x, grandparentOf, ?z) :- (?x, parentOf, !y) -> (!y, parentOf, ?z)",
+				"-(Ozan, ?p, ?o)",
+			},
+			want: []string{"(Ali, parentOf, Ayse)"},
+		},
+		{
+			desc: "update with a rule",
+			lines: []string{
+				"(Ozan, parentOf, Ali)",
+				"(Ali, parentOf, Ayse)",
+				"(?x, grandparentOf, ?z) :- (?x, parentOf, !y) -> (!y, parentOf, ?z)",
+				"update (Ozan, ?p, ?o) set (Ozan, ?p, Veli)",
+			},
+			want: []string{"(Ali, parentOf, Ayse)", "(Ozan, parentOf, Veli)"},
+		},
+		{
+			desc: "update in a graph",
+			lines: []string{
+				"(Ozan, worksIn, Sales)",
+				"use HR",
+				"(Ali, worksIn, Sales)",
+				"update (?x, worksIn, Sales) set (?x, worksIn, Marketing)",
+			},
+			want: []string{"(Ali, worksIn, Marketing)", "(Ozan, worksIn, Sales)"},
+		},
+		{
+			desc: "insert of a non-finite number",
+			lines: []string{
+				"(Ozan, age, 24.5)",
+				"(Ali, age, 30.5)",
+				"insert (?x, huge, ?h) where (?x, age, ?a) -> bind ?a * 1e308 as ?h",
+			},
+			want:    []string{"(Ali, age, 30.5)", "(Ozan, age, 24.5)"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			fs, err := filestore.New()
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			i := New(p, fs)
+			for n, l := range tc.lines {
+				e, err := p.ParseLine(l)
+				if err != nil {
+					t.Fatalf("failed to parse %q: %v", l, err)
+				}
+				err = execute(i, e)
+				if last := n == len(tc.lines)-1; last && tc.wantErr {
+					if err == nil {
+						t.Fatalf("expected an error executing %q, got none", l)
+					}
+				} else if err != nil {
+					t.Fatalf("failed to execute %q: %v", l, err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, asserted(t, p, fs)); diff != "" {
+				t.Errorf("asserted facts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFactIDsInFile(t *testing.T) {
+	known := &parser.Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: parser.SubjectObject{Value: "Go"}}
+	lines := fmt.Sprintf("(Ozan, knows, Go)\n(%s, approvedBy, METU)\n", parser.FactID(store.FactHash(known)))
+	want := []string{"((Ozan, knows, Go), approvedBy, METU)", "(Ozan, knows, Go)"}
+
+	tests := []struct {
+		desc   string
+		reload bool
+	}{
+		{desc: "load"},
+		{desc: "reload", reload: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "facts.sxql")
+			if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			p := parser.New()
+			fs, err := filestore.New()
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			i := New(p, fs)
+			if tc.reload {
+				e, err := p.ParseLine(fmt.Sprintf("reload graph HR from %q", path))
+				if err != nil {
+					t.Fatalf("failed to parse reload: %v", err)
+				}
+				if err := i.executeReload(e.Reload); err != nil {
+					t.Fatalf("failed to reload: %v", err)
+				}
+			} else {
+				file, err := p.ParseFile(path)
+				if err != nil {
+					t.Fatalf("failed to parse file: %v", err)
+				}
+				for _, e := range file.Expressions {
+					if err := execute(i, e); err != nil {
+						t.Fatalf("failed to execute %s: %v", e.Pretty(), err)
+					}
+				}
+			}
+			if diff := cmp.Diff(want, asserted(t, p, fs)); diff != "" {
+				t.Errorf("asserted facts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// execute executes the expressions that change the store, returning their
+// errors instead of printing them.
+func execute(i *Interpreter, e *parser.Expression) error {
+	if err := parser.ResolveFactIDs(e, i.store.GetByID); err != nil {
+		return err
+	}
+	switch {
+	case e.Rule != nil:
+		return i.executeRule(e.Rule)
+	case e.Construct != nil:
+		return i.executeConstruct(e.Construct)
+	case e.Update != nil:
+		return i.executeUpdate(e.Update)
+	case e.Delete != nil:
+		return i.executeDelete(e.Delete)
+	case e.Fact != nil:
+		return i.executeFact(e.Fact)
+	case e.Use != nil:
+		i.graph = e.Use.Graph
+		return nil
+	}
+	return nil
+}
+
+// asserted returns the asserted facts of the store, sorted.
+func asserted(t *testing.T, p *parser.Parser, s store.Store) []string {
+	t.Helper()
+	e, err := p.ParseLine("(?s, ?p, ?o)")
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	q, err := store.QueryFromAST(e.Query)
+	if err != nil {
+		t.Fatalf("failed to convert query: %v", err)
+	}
+	res, err := store.Evaluate(s.Asserted(), q)
+	if err != nil {
+		t.Fatalf("failed to evaluate query: %v", err)
+	}
+	got := []string{}
+	for _, row := range res.Rows {
+		got = append(got, row.Facts[0].Pretty())
+	}
+	sort.Strings(got)
+	return got
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+// resolveReferences replaces the anchors referenced by the expression with the
+// facts they stand for, and records the anchor or the prefix the expression
+// declares, if any. Anchors are resolved in the order expressions are parsed,
+// so an anchor can only be referenced after it is defined. Fact IDs are left
+// for ResolveFactIDs, as the facts they reference may not be stored yet.
+func (p *Parser) resolveReferences(e *Expression) error {
+	if e.Prefix != nil {
+		return p.prefixes.declare(e.Prefix.Name, e.Prefix.Namespace)
+	}
+	if err := resolveExpression(e, p.anchor); err != nil {
+		return err
+	}
+	if e.Anchor != nil {
+		p.anchors[e.Anchor.Name] = e.Anchor.Fact.Copy()
+	}
+	return nil
+}
+
+// referenceResolver returns the fact referenced by the anchor or the ID, or
+// nil if it leaves the reference as it is.
+type referenceResolver func(anchor *string, id *FactID) (*Fact, error)
+
+// resolveExpression resolves the references of the expression, including the
+// ones nested in its facts and patterns.
+func resolveExpression(e *Expression, resolve referenceResolver) error {
+	var err error
+	switch {
+	case e.Anchor != nil:
+		err = resolveFact(e.Anchor.Fact, resolve)
+	case e.Fact != nil:
+		err = resolveFact(e.Fact, resolve)
+	case e.Rule != nil:
+		if err = resolveQuery(e.Rule.Head, resolve); err == nil {
+			err = resolveQuery(e.Rule.Body, resolve)
+		}
+	case e.Construct != nil:
+		for _, t := range e.Construct.Templates {
+			if err = resolveQuery(t, resolve); err != nil {
+				return err
+			}
+		}
+		err = resolveQuery(e.Construct.Where, resolve)
+	case e.Update != nil:
+		if err = resolveQuery(e.Update.Match, resolve); err == nil {
+			err = resolveQuery(e.Update.Set, resolve)
+		}
+	case e.Describe != nil:
+		if e.Describe.ID != nil {
+			var f *Fact
+			if f, err = resolve(nil, e.Describe.ID); err == nil && f != nil {
+				e.Describe.Fact = f
+			}
+		}
+	case e.Query != nil:
+		err = resolveQuery(e.Query, resolve)
+	case e.DeleteID != nil:
+		var f *Fact
+		if f, err = resolve(nil, e.DeleteID); err == nil && f != nil {
+			e.Delete, e.DeleteID = QueryFromFact(f), nil
+		}
+	case e.Delete != nil:
+		err = resolveQuery(e.Delete, resolve)
+	}
+	return err
+}
+
+// anchor returns the fact labelled by the anchor, leaving fact IDs unresolved.
+func (p *Parser) anchor(name *string, _ *FactID) (*Fact, error) {
+	if name == nil {
+		return nil, nil
+	}
+	f, ok := p.anchors[*name]
+	if !ok {
+		return nil, fmt.Errorf("undefined anchor: %s", *name)
+	}
+	return f.Copy(), nil
+}
+
+func resolveFact(f *Fact, resolve referenceResolver) error {
+	subject, err := resolve(f.SubjectAnchor, f.SubjectID)
+	if err != nil {
+		return err
+	}
+	if subject != nil {
+		f.SubjectFact, f.SubjectAnchor, f.SubjectID = subject, nil, nil
+	}
+	if f.SubjectFact != nil {
+		if err = resolveFact(f.SubjectFact, resolve); err != nil {
+			return err
+		}
+	}
+	object, err := resolve(f.ObjectAnchor, f.ObjectID)
+	if err != nil {
+		return err
+	}
+	if object != nil {
+		f.ObjectFact, f.ObjectAnchor, f.ObjectID = object, nil, nil
+	}
+	if f.ObjectFact != nil {
+		if err = resolveFact(f.ObjectFact, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveQuery(q *Query, resolve referenceResolver) error {
+	if q == nil {
+		return nil
+	}
+	subject, err := resolve(q.SubjectAnchor, q.SubjectID)
+	if err != nil {
+		return err
+	}
+	if subject != nil {
+		q.SubjectQuery, q.SubjectAnchor, q.SubjectID = QueryFromFact(subject), nil, nil
+	}
+	object, err := resolve(q.ObjectAnchor, q.ObjectID)
+	if err != nil {
+		return err
+	}
+	if object != nil {
+		q.ObjectQuery, q.ObjectAnchor, q.ObjectID = QueryFromFact(object), nil, nil
+	}
+	nested := []*Query{q.SubjectQuery, q.ObjectQuery, q.Exists, q.NotExists, q.LinkedQuery}
+	if q.Within != nil {
+		nested = append(nested, q.Within.Query)
+	}
+	for _, n := range append(nested, q.Union...) {
+		if err := resolveQuery(n, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryFromFact returns the pattern matching exactly the fact.
+func QueryFromFact(f *Fact) *Query {
+	q := &Query{
+		Subject:   ptrutils.PtrFromPtr(f.Subject),
+		SubjectID: f.SubjectID,
+		Predicate: ptrutils.Ptr(f.Predicate),
+		ObjectID:  f.ObjectID,
+	}
+	if f.SubjectFact != nil {
+		q.SubjectQuery = QueryFromFact(f.SubjectFact)
+	}
+	if f.Object != nil {
+		q.Object = f.Object.Copy()
+	}
+	if f.ObjectFact != nil {
+		q.ObjectQuery = QueryFromFact(f.ObjectFact)
+	}
+	return q
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Expr is a boolean disjunction of conjunctions, the loosest binding level of
+// the expressions used by filter and bind clauses. From loosest to tightest,
+// expressions are built from or, and, not, comparisons, + and -, * / and %,
+// unary minus, and primary values.
+type Expr struct {
+	Or []*Conjunction `@@ ( "or" @@ )*`
+}
+
+type Conjunction struct {
+	And []*Negation `@@ ( "and" @@ )*`
+}
+
+type Negation struct {
+	Not        bool        `@"not"?`
+	Comparison *Comparison `@@`
+}
+
+type Comparison struct {
+	Left     *Sum    `@@`
+	Operator *string `[ @( "<" "=" | ">" "=" | "!" "=" | "=" "~" | "^" "=" | "$" "=" | "*" "=" | "<" | ">" | "=" )`
+	Right    *Sum    `  @@ ]`
+}
+
+type Sum struct {
+	Left *Product `@@`
+	Rest []*SumOp `@@*`
+}
+
+type SumOp struct {
+	Operator string   `@( "+" | "-" )`
+	Operand  *Product `@@`
+}
+
+type Product struct {
+	Left *Unary       `@@`
+	Rest []*ProductOp `@@*`
+}
+
+type ProductOp struct {
+	Operator string `@( "*" | "/" | "%" )`
+	Operand  *Unary `@@`
+}
+
+type Unary struct {
+	Negative bool     `@"-"?`
+	Operand  *Primary `@@`
+}
+
+// Primary is a literal, a variable, a function call, a subject or a
+// parenthesized expression.
+type Primary struct {
+	Number    *float64      `  @Number`
+	Integer   *int64        `| @Int`
+	Date      *Date         `| @Date`
+	Timestamp *time.Time    `| @Timestamp`
+	String    *StringObject `| @@`
+	Bool      *string       `| @( "true" | "false" )`
+	Variable  *string       `| @QueryIdent`
+	Call      *Call         `| @@`
+	Subject   *string       `| @Ident`
+	Sub       *Expr         `| "(" @@ ")"`
+}
+
+type Call struct {
+	Function string  `@Ident "("`
+	Args     []*Expr `[ @@ ( "," @@ )* ] ")"`
+}
+
+// Bind binds the value of the expression to the variable.
+type Bind struct {
+	Expr     *Expr  `@@ "as"`
+	Variable string `@QueryIdent`
+}
+
+func (e *Expr) Pretty() string {
+	operands := []string{}
+	for _, c := range e.Or {
+		operands = append(operands, c.Pretty())
+	}
+	return strings.Join(operands, " or ")
+}
+
+func (c *Conjunction) Pretty() string {
+	operands := []string{}
+	for _, n := range c.And {
+		operands = append(operands, n.Pretty())
+	}
+	return strings.Join(operands, " and ")
+}
+
+func (n *Negation) Pretty() string {
+	if n.Not {
+		return "not " + n.Comparison.Pretty()
+	}
+	return n.Comparison.Pretty()
+}
+
+func (c *Comparison) Pretty() string {
+	if c.Operator == nil {
+		return c.Left.Pretty()
+	}
+	return fmt.Sprintf("%s %s %s", c.Left.Pretty(), *c.Operator, c.Right.Pretty())
+}
+
+func (s *Sum) Pretty() string {
+	var sb strings.Builder
+	sb.WriteString(s.Left.Pretty())
+	for _, op := range s.Rest {
+		sb.WriteString(fmt.Sprintf(" %s %s", op.Operator, op.Operand.Pretty()))
+	}
+	return sb.String()
+}
+
+func (p *Product) Pretty() string {
+	var sb strings.Builder
+	sb.WriteString(p.Left.Pretty())
+	for _, op := range p.Rest {
+		sb.WriteString(fmt.Sprintf(" %s %s", op.Operator, op.Operand.Pretty()))
+	}
+	return sb.String()
+}
+
+func (u *Unary) Pretty() string {
+	if u.Negative {
+		return "-" + u.Operand.Pretty()
+	}
+	return u.Operand.Pretty()
+}
+
+func (p *Primary) Pretty() string {
+	switch {
+	case p.Number != nil:
+		return NumberObject{Value: *p.Number}.String()
+	case p.Integer != nil:
+		return IntegerObject{Value: *p.Integer}.String()
+	case p.Date != nil:
+		return DateObject{Value: *p.Date}.String()
+	case p.Timestamp != nil:
+		return TimestampObject{Value: *p.Timestamp}.String()
+	case p.String != nil:
+		return p.String.String()
+	case p.Bool != nil:
+		return *p.Bool
+	case p.Variable != nil:
+		return *p.Variable
+	case p.Call != nil:
+		return p.Call.Pretty()
+	case p.Subject != nil:
+		return *p.Subject
+	case p.Sub != nil:
+		return "(" + p.Sub.Pretty() + ")"
+	}
+	return ""
+}
+
+func (c *Call) Pretty() string {
+	args := []string{}
+	for _, a := range c.Args {
+		args = append(args, a.Pretty())
+	}
+	return fmt.Sprintf("%s(%s)", c.Function, strings.Join(args, ", "))
+}
+
+func (b *Bind) Pretty() string {
+	return fmt.Sprintf("%s as %s", b.Expr.Pretty(), b.Variable)
+}
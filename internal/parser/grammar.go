@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ozansz/semantix/pkg/ptrutils"
 )
@@ -16,16 +19,118 @@ type File struct {
 }
 
 type Expression struct {
-	Fact  *Fact  `  @@`
-	Query *Query `| @@`
+	Rule      *Rule      `  @@`
+	Construct *Construct `| @@`
+	Update    *Update    `| @@`
+	Describe  *Describe  `| @@`
+	Use       *Use       `| @@`
+	Drop      *DropGraph `| @@`
+	Reload    *Reload    `| @@`
+	Anchor    *Anchor    `| @@`
+	Prefix    *Prefix    `| @@`
+	Fact      *Fact      `| @@`
+	Query     *Query     `| @@`
+	Modifiers *Modifiers `  @@?`
+	DeleteID  *FactID    `| "-" @FactID`
+	Delete    *Query     `| "-" @@`
+}
+
+// Rule derives the facts matching Head from the solutions of Body, e.g.
+// (?x, grandparentOf, ?z) :- (?x, parentOf, !y) -> (!y, parentOf, ?z).
+type Rule struct {
+	Head *Query `@@ ":" "-"`
+	Body *Query `@@`
+}
+
+// Construct instantiates its fact templates for every solution of Where,
+// returning the facts or, if Insert, adding them to the store, e.g.
+// insert (?x, is, Engineer) where (?x, knows, CS).
+type Construct struct {
+	Insert    bool       `( @"insert" | "construct" )`
+	Templates []*Query   `( "{" @@ ( "," @@ )* "}" | @@ )`
+	Where     *Query     `"where" @@`
+	Modifiers *Modifiers `@@?`
+}
+
+// Update rewrites the facts matched by the first pattern of Match into the
+// facts instantiated from Set, e.g.
+// update (?x, age, ?a) -> bind ?a + 1 as ?b set (?x, age, ?b).
+type Update struct {
+	Match *Query `"update" @@`
+	Set   *Query `"set" @@`
+}
+
+// Describe lists the facts around the subject, up to Depth hops away.
+type Describe struct {
+	Subject string  `"describe" ( @Ident`
+	ID      *FactID `          | @FactID )`
+	Depth   *int    `[ "depth" @Int ]`
+	// Fact is the fact referenced by ID, set once the reference is resolved.
+	Fact *Fact
+}
+
+// Use selects the graph facts are added to and queries read, e.g. use HR.
+// Queries read every graph when the default graph is used.
+type Use struct {
+	Graph string `"use" @Ident`
+}
+
+// DropGraph removes the facts of a graph, e.g. drop graph HR.
+type DropGraph struct {
+	Graph string `"drop" "graph" @Ident`
+}
+
+// Reload replaces the facts of a graph with the facts of a file, e.g.
+// reload graph HR from "hr.sxql".
+type Reload struct {
+	Graph string `"reload" "graph" @Ident`
+	Path  string `"from" @String`
+}
+
+// Modifiers post-process the solutions of a query.
+type Modifiers struct {
+	Aggregates []*Aggregate `(?= "aggregate" | "order" | "limit" | "offset" | "match" | "from" ) [ "aggregate" @@ ( "," @@ )*`
+	GroupBy    []string     `  [ "group" "by" @QueryIdent ( "," @QueryIdent )* ] ]`
+	OrderBy    []*OrderKey  `[ "order" "by" @@ ( "," @@ )* ]`
+	Limit      *int         `[ "limit" @Int ]`
+	Offset     *int         `[ "offset" @Int ]`
+	// Scope selects the facts patterns match: the asserted ones, the ones
+	// quoted in other facts, or any of them.
+	Scope *string `[ "match" @( "asserted" | "quoted" | "any" ) ]`
+	// From restricts the query to the facts of the named graphs.
+	From []string `[ "from" @Ident ( "," @Ident )* ]`
+}
+
+type OrderKey struct {
+	Variable   string `@QueryIdent`
+	Descending bool   `[ @"desc" | "asc" ]`
+}
+
+// Aggregate summarises the values bound to a variable, e.g. avg(!a) as ?avg.
+type Aggregate struct {
+	Function string `@( "count" | "sum" | "avg" | "min" | "max" ) "("`
+	Variable string `@( QueryIdent | "*" ) ")"`
+	As       string `"as" @QueryIdent`
 }
 
 type Fact struct {
-	Subject     *string `"(" ( @Ident`
-	SubjectFact *Fact   `    | @@ )`
-	Predicate   string  `"," @Ident`
-	Object      Object  `"," ( @@`
-	ObjectFact  *Fact   `    | @@ ) ")"`
+	Subject       *string `"(" ( @Ident`
+	SubjectAnchor *string `    | @AnchorIdent`
+	SubjectID     *FactID `    | @FactID`
+	SubjectFact   *Fact   `    | @@ )`
+	Predicate     string  `"," @Ident`
+	ObjectAnchor  *string `"," ( @AnchorIdent`
+	ObjectID      *FactID `    | @FactID`
+	Object        Object  `    | @@`
+	ObjectFact    *Fact   `    | @@ ) ")"`
+}
+
+// Anchor labels the fact, so that it can be referenced by its name as the
+// subject or object of facts and queries parsed later, e.g.
+// _f1 = (Ozan, knows, CS). The fact is also asserted.
+type Anchor struct {
+	Name string `@AnchorIdent "="`
+	Fact *Fact  `@@`
 }
 
 type QueryKind int
@@ -38,27 +143,88 @@ const (
 )
 
 type Query struct {
-	Subject      *string `"(" ( @Ident`
-	SubjectVar   *string `    | @QueryIdent`
-	SubjectQuery *Query  `    | @@ )`
-	Predicate    *string `"," ( @Ident`
-	PredicateVar *string `    | @QueryIdent )`
-	Object       Object  `"," ( @@`
-	ObjectVar    *string `    | @QueryIdent`
-	ObjectQuery  *Query  `    | @@ ) ")"`
-	LinkedQuery  *Query  `[ "-" ">" @@ ]`
-	IDInFile     string
-	Kind         QueryKind
+	Optional         bool           `@"optional"?`
+	Union            []*Query       `( "{" @@ ( "|" @@ )* "}"`
+	Exists           *Query         `| "exists" "{" @@ "}"`
+	NotExists        *Query         `| "not" "exists" "{" @@ "}"`
+	Within           *Within        `| @@`
+	Filter           *Expr          `| "filter" @@`
+	Bind             *Bind          `| "bind" @@`
+	Subject          *string        `| "(" ( @Ident`
+	SubjectVar       *string        `    | @QueryIdent`
+	SubjectNegated   *string        `    | "~" @Ident`
+	SubjectAnchor    *string        `    | @AnchorIdent`
+	SubjectID        *FactID        `    | @FactID`
+	SubjectQuery     *Query         `    | @@ )`
+	PredicatePath    *PredicatePath `"," ( @@`
+	Predicate        *string        `    | @Ident`
+	PredicateVar     *string        `    | @QueryIdent`
+	PredicateNegated *string        `    | "~" @Ident )`
+	ObjectFilter     *ObjectFilter  `"," ( @@`
+	Object           Object         `    | @@`
+	ObjectVar        *string        `    | @QueryIdent`
+	ObjectLang       *LangTag       `    | @LangTag`
+	ObjectNegated    Object         `    | "~" @@`
+	ObjectAnchor     *string        `    | @AnchorIdent`
+	ObjectID         *FactID        `    | @FactID`
+	ObjectQuery      *Query         `    | @@ ) ")" )`
+	LinkedQuery      *Query         `[ "-" ">" @@ ]`
+	IDInFile         string
+	Kind             QueryKind
+}
+
+// Within evaluates a query chain against the facts an agent holds under the
+// predicate, e.g. within (Ezgi, thinks) { (?x, is, Person) }.
+type Within struct {
+	Agent     *string `"within" "(" ( @Ident`
+	AgentVar  *string `            | @QueryIdent )`
+	Predicate string  `"," @Ident ")"`
+	Query     *Query  `"{" @@ "}"`
+}
+
+// PredicatePath matches chains of facts whose predicates follow the path,
+// e.g. subtopicOf+, subtopicOf{1,3}, knows|likes or ^knows.
+type PredicatePath struct {
+	Alternatives []*PathStep `(?! Ident "," ) @@ ( "|" @@ )*`
+}
+
+// PathStep follows the predicate, against its direction if inverse, repeated
+// as many times as its modifier allows: + (at least once), * (any number of
+// times), {n} (exactly n times) or {n,m} (n to m times).
+type PathStep struct {
+	Inverse   bool    `@"^"?`
+	Predicate string  `@Ident`
+	Modifier  *string `[ @( "+" | "*" )`
+	Min       *int    `| "{" @Int`
+	Max       *int    `  [ "," @Int ] "}" ]`
+}
+
+// ObjectFilter constrains the object of a query pattern by a comparison
+// (> 20, ^= "Oz", = 24.0) or by an inclusive numeric range (100..200).
+type ObjectFilter struct {
+	Low      *float64 `  @( Number | Int ) Range`
+	High     *float64 `  @( Number | Int )`
+	Operator *string  `| @( "<" "=" | ">" "=" | "!" "=" | "<" | ">" | "^" "=" | "$" "=" | "*" "=" | "=" "~" | "=" )`
+	Value    Object   `  @@`
 }
 
 type ObjectKind int
 
+// Kinds are numbered in the order they were introduced, since fact IDs and
+// store records depend on them.
 const (
 	ObjectKindSubject ObjectKind = iota
 	ObjectKindString
 	ObjectKindNumber
+	ObjectKindInteger
+	ObjectKindBool
+	ObjectKindDate
+	ObjectKindTimestamp
 )
 
+// DateLayout is the layout of date literals, such as 1999-05-04.
+const DateLayout = "2006-01-02"
+
 type Object interface {
 	String() string
 	IsSubject() bool
@@ -72,37 +238,138 @@ type SubjectObject struct {
 	Value string `@Ident`
 }
 
+// StringObject is a string, optionally annotated with either a language tag,
+// "Ozan"@en, or a datatype, "42"^^xsd:integer.
 type StringObject struct {
-	Value string `@String`
+	Value    string  `@String`
+	Lang     LangTag `[ @LangTag`
+	Datatype string  `| "^" "^" @Ident ]`
 }
 
 type NumberObject struct {
 	Value float64 `@Number`
 }
 
-func (s SubjectObject) String() string { return s.Value }
-func (s StringObject) String() string  { return fmt.Sprintf("%q", s.Value) }
-func (n NumberObject) String() string  { return fmt.Sprintf("%f", n.Value) }
+type IntegerObject struct {
+	Value int64 `@Int`
+}
+
+type BoolObject struct {
+	Value Boolean `@( "true" | "false" )`
+}
+
+// DateObject is a calendar day, such as 1999-05-04.
+type DateObject struct {
+	Value Date `@Date`
+}
 
-func (s SubjectObject) IsSubject() bool { return true }
-func (s StringObject) IsSubject() bool  { return false }
-func (n NumberObject) IsSubject() bool  { return false }
+// TimestampObject is an RFC 3339 instant, such as 2023-07-08T14:30:00Z. The
+// zone offset it is written in is kept.
+type TimestampObject struct {
+	Value time.Time `@Timestamp`
+}
 
-func (s SubjectObject) IsNumber() bool { return false }
-func (s StringObject) IsNumber() bool  { return false }
-func (n NumberObject) IsNumber() bool  { return true }
+// LangTag is a language tag, such as en or en-us, captured without its @ and
+// in lower case, as tags are case insensitive.
+type LangTag string
 
-func (s SubjectObject) Copy() Object { return SubjectObject{Value: s.Value} }
-func (s StringObject) Copy() Object  { return StringObject{Value: s.Value} }
-func (n NumberObject) Copy() Object  { return NumberObject{Value: n.Value} }
+func (l *LangTag) Capture(values []string) error {
+	*l = LangTag(strings.ToLower(strings.TrimPrefix(values[0], "@")))
+	return nil
+}
 
-func (s SubjectObject) Kind() ObjectKind { return ObjectKindSubject }
-func (s StringObject) Kind() ObjectKind  { return ObjectKindString }
-func (n NumberObject) Kind() ObjectKind  { return ObjectKindNumber }
+// Matches reports whether the tag is the range or one of its subtags, e.g. en
+// matches en and en-us.
+func (l LangTag) Matches(r LangTag) bool {
+	return l == r || strings.HasPrefix(string(l), string(r)+"-")
+}
+
+// FormatNumber returns the shortest text that parses back to the number, in
+// positional notation unless its magnitude is below 1e-6 or at least 1e21. It
+// always has a fraction or an exponent, so that it is read as a number, not as
+// an integer. Fact IDs, store records and the printed facts all use it. NaN
+// and infinities have no literal form, so stores reject facts holding them.
+func FormatNumber(v float64) string {
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(v, format, -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Boolean is captured from the true and false keywords.
+type Boolean bool
+
+func (b *Boolean) Capture(values []string) error {
+	*b = values[0] == "true"
+	return nil
+}
+
+// Date is captured from date literals, as midnight UTC of the day.
+type Date struct {
+	time.Time
+}
+
+func (d *Date) Capture(values []string) error {
+	t, err := time.Parse(DateLayout, values[0])
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
 
-func (s SubjectObject) InnerValue() any { return s.Value }
-func (s StringObject) InnerValue() any  { return s.Value }
-func (n NumberObject) InnerValue() any  { return n.Value }
+func (s SubjectObject) String() string   { return s.Value }
+func (s StringObject) String() string    { return s.format() }
+func (n NumberObject) String() string    { return FormatNumber(n.Value) }
+func (i IntegerObject) String() string   { return strconv.FormatInt(i.Value, 10) }
+func (b BoolObject) String() string      { return strconv.FormatBool(bool(b.Value)) }
+func (d DateObject) String() string      { return d.Value.Format(DateLayout) }
+func (t TimestampObject) String() string { return t.Value.Format(time.RFC3339Nano) }
+
+func (s SubjectObject) IsSubject() bool   { return true }
+func (s StringObject) IsSubject() bool    { return false }
+func (n NumberObject) IsSubject() bool    { return false }
+func (i IntegerObject) IsSubject() bool   { return false }
+func (b BoolObject) IsSubject() bool      { return false }
+func (d DateObject) IsSubject() bool      { return false }
+func (t TimestampObject) IsSubject() bool { return false }
+
+func (s SubjectObject) IsNumber() bool   { return false }
+func (s StringObject) IsNumber() bool    { return false }
+func (n NumberObject) IsNumber() bool    { return true }
+func (i IntegerObject) IsNumber() bool   { return true }
+func (b BoolObject) IsNumber() bool      { return false }
+func (d DateObject) IsNumber() bool      { return false }
+func (t TimestampObject) IsNumber() bool { return false }
+
+func (s SubjectObject) Copy() Object   { return SubjectObject{Value: s.Value} }
+func (s StringObject) Copy() Object    { return s }
+func (n NumberObject) Copy() Object    { return NumberObject{Value: n.Value} }
+func (i IntegerObject) Copy() Object   { return IntegerObject{Value: i.Value} }
+func (b BoolObject) Copy() Object      { return BoolObject{Value: b.Value} }
+func (d DateObject) Copy() Object      { return DateObject{Value: d.Value} }
+func (t TimestampObject) Copy() Object { return TimestampObject{Value: t.Value} }
+
+func (s SubjectObject) Kind() ObjectKind   { return ObjectKindSubject }
+func (s StringObject) Kind() ObjectKind    { return ObjectKindString }
+func (n NumberObject) Kind() ObjectKind    { return ObjectKindNumber }
+func (i IntegerObject) Kind() ObjectKind   { return ObjectKindInteger }
+func (b BoolObject) Kind() ObjectKind      { return ObjectKindBool }
+func (d DateObject) Kind() ObjectKind      { return ObjectKindDate }
+func (t TimestampObject) Kind() ObjectKind { return ObjectKindTimestamp }
+
+func (s SubjectObject) InnerValue() any   { return s.Value }
+func (s StringObject) InnerValue() any    { return s.Value }
+func (n NumberObject) InnerValue() any    { return n.Value }
+func (i IntegerObject) InnerValue() any   { return i.Value }
+func (b BoolObject) InnerValue() any      { return bool(b.Value) }
+func (d DateObject) InnerValue() any      { return d.Value.Time }
+func (t TimestampObject) InnerValue() any { return t.Value }
 
 func (f *File) Pretty() string {
 	var sb strings.Builder
@@ -116,7 +383,34 @@ func (f *File) Pretty() string {
 func (e *Expression) Pretty() string {
 	space := strings.Repeat(" ", prettyExprIndent)
 	var sb strings.Builder
-	if e.Fact != nil {
+	if e.Rule != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Rule.Pretty())
+	} else if e.Construct != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Construct.Pretty())
+	} else if e.Update != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Update.Pretty())
+	} else if e.Describe != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Describe.Pretty())
+	} else if e.Use != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Use.Pretty())
+	} else if e.Drop != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Drop.Pretty())
+	} else if e.Reload != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Reload.Pretty())
+	} else if e.Prefix != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Prefix.Pretty())
+	} else if e.Anchor != nil {
+		sb.WriteString(space)
+		sb.WriteString(e.Anchor.Pretty())
+	} else if e.Fact != nil {
 		sb.WriteString(space)
 		sb.WriteString(e.Fact.Pretty())
 	} else if e.Query != nil {
@@ -124,50 +418,298 @@ func (e *Expression) Pretty() string {
 		sb.WriteString(": ")
 		sb.WriteString(space[:len(space)-len(e.Query.IDInFile)])
 		sb.WriteString(e.Query.Pretty())
+		if e.Modifiers != nil {
+			sb.WriteRune(' ')
+			sb.WriteString(e.Modifiers.Pretty())
+		}
+	} else if e.DeleteID != nil {
+		sb.WriteString(space[:len(space)-1])
+		sb.WriteRune('-')
+		sb.WriteString(e.DeleteID.String())
+	} else if e.Delete != nil {
+		sb.WriteString(space[:len(space)-1])
+		sb.WriteRune('-')
+		sb.WriteString(e.Delete.Pretty())
 	}
 	return sb.String()
 }
 
 func (s *Query) Pretty() string {
 	var sb strings.Builder
+	if s.Optional {
+		sb.WriteString("optional ")
+	}
+	if len(s.Union) > 0 {
+		sb.WriteString("{ ")
+		for i, u := range s.Union {
+			if i > 0 {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(u.Pretty())
+		}
+		sb.WriteString(" }")
+		return s.prettyLinked(&sb)
+	}
+	if s.Exists != nil {
+		sb.WriteString("exists { ")
+		sb.WriteString(s.Exists.Pretty())
+		sb.WriteString(" }")
+		return s.prettyLinked(&sb)
+	}
+	if s.NotExists != nil {
+		sb.WriteString("not exists { ")
+		sb.WriteString(s.NotExists.Pretty())
+		sb.WriteString(" }")
+		return s.prettyLinked(&sb)
+	}
+	if s.Within != nil {
+		sb.WriteString(s.Within.Pretty())
+		return s.prettyLinked(&sb)
+	}
+	if s.Filter != nil {
+		sb.WriteString("filter ")
+		sb.WriteString(s.Filter.Pretty())
+		return s.prettyLinked(&sb)
+	}
+	if s.Bind != nil {
+		sb.WriteString("bind ")
+		sb.WriteString(s.Bind.Pretty())
+		return s.prettyLinked(&sb)
+	}
 	sb.WriteRune('(')
 
 	if s.Subject != nil {
 		sb.WriteString(*s.Subject)
 	} else if s.SubjectVar != nil {
 		sb.WriteString(*s.SubjectVar)
+	} else if s.SubjectNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(*s.SubjectNegated)
+	} else if s.SubjectAnchor != nil {
+		sb.WriteString(*s.SubjectAnchor)
+	} else if s.SubjectID != nil {
+		sb.WriteString(s.SubjectID.String())
+	} else if s.SubjectQuery != nil {
+		sb.WriteString(s.SubjectQuery.Pretty())
 	}
 	sb.WriteString(", ")
-	if s.Predicate != nil {
+	if s.PredicatePath != nil {
+		sb.WriteString(s.PredicatePath.Pretty())
+	} else if s.Predicate != nil {
 		sb.WriteString(*s.Predicate)
 	} else if s.PredicateVar != nil {
 		sb.WriteString(*s.PredicateVar)
+	} else if s.PredicateNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(*s.PredicateNegated)
 	}
 	sb.WriteString(", ")
 	if s.Object != nil {
 		sb.WriteString(s.Object.String())
+	} else if s.ObjectFilter != nil {
+		sb.WriteString(s.ObjectFilter.Pretty())
 	} else if s.ObjectVar != nil {
 		sb.WriteString(*s.ObjectVar)
+	} else if s.ObjectLang != nil {
+		sb.WriteString("@" + string(*s.ObjectLang))
+	} else if s.ObjectNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(s.ObjectNegated.String())
+	} else if s.ObjectAnchor != nil {
+		sb.WriteString(*s.ObjectAnchor)
+	} else if s.ObjectID != nil {
+		sb.WriteString(s.ObjectID.String())
 	} else if s.ObjectQuery != nil {
 		sb.WriteString(s.ObjectQuery.Pretty())
 	}
 
 	sb.WriteRune(')')
 
+	return s.prettyLinked(&sb)
+}
+
+func (s *Query) prettyLinked(sb *strings.Builder) string {
 	if s.LinkedQuery != nil {
 		sb.WriteString(" -> ")
 		sb.WriteString(s.LinkedQuery.Pretty())
 	}
+	return sb.String()
+}
 
+func (r *Rule) Pretty() string {
+	return fmt.Sprintf("%s :- %s", r.Head.Pretty(), r.Body.Pretty())
+}
+
+func (c *Construct) Pretty() string {
+	var sb strings.Builder
+	if c.Insert {
+		sb.WriteString("insert ")
+	} else {
+		sb.WriteString("construct ")
+	}
+	if len(c.Templates) == 1 {
+		sb.WriteString(c.Templates[0].Pretty())
+	} else {
+		templates := []string{}
+		for _, t := range c.Templates {
+			templates = append(templates, t.Pretty())
+		}
+		sb.WriteString(fmt.Sprintf("{ %s }", strings.Join(templates, ", ")))
+	}
+	sb.WriteString(" where ")
+	sb.WriteString(c.Where.Pretty())
+	if c.Modifiers != nil {
+		sb.WriteRune(' ')
+		sb.WriteString(c.Modifiers.Pretty())
+	}
 	return sb.String()
 }
 
+func (u *Update) Pretty() string {
+	return fmt.Sprintf("update %s set %s", u.Match.Pretty(), u.Set.Pretty())
+}
+
+func (d *Describe) Pretty() string {
+	subject := d.Subject
+	if d.ID != nil {
+		subject = d.ID.String()
+	}
+	if d.Depth == nil {
+		return fmt.Sprintf("describe %s", subject)
+	}
+	return fmt.Sprintf("describe %s depth %d", subject, *d.Depth)
+}
+
+// FactID references a fact by its ID, written in full width as the store
+// prints it, e.g. #0123456789, so that a comment like "#1 ..." stays one. The
+// lexer can not tell where a reference is expected, so "#" followed by exactly
+// ten digits is always a reference: a comment starting with such a number must
+// put a space after the "#" or start with "--" instead.
+type FactID uint32
+
+func (id *FactID) Capture(values []string) error {
+	v, err := strconv.ParseUint(strings.TrimPrefix(values[0], "#"), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid fact ID %s: %v", values[0], err)
+	}
+	*id = FactID(v)
+	return nil
+}
+
+func (id FactID) String() string {
+	return fmt.Sprintf("#%010d", uint32(id))
+}
+
+func (u *Use) Pretty() string {
+	return "use " + u.Graph
+}
+
+func (d *DropGraph) Pretty() string {
+	return "drop graph " + d.Graph
+}
+
+func (r *Reload) Pretty() string {
+	return fmt.Sprintf("reload graph %s from %q", r.Graph, r.Path)
+}
+
+func (m *Modifiers) Pretty() string {
+	clauses := []string{}
+	if len(m.Aggregates) > 0 {
+		aggregates := []string{}
+		for _, a := range m.Aggregates {
+			aggregates = append(aggregates, a.Pretty())
+		}
+		clauses = append(clauses, "aggregate "+strings.Join(aggregates, ", "))
+	}
+	if len(m.GroupBy) > 0 {
+		clauses = append(clauses, "group by "+strings.Join(m.GroupBy, ", "))
+	}
+	if len(m.OrderBy) > 0 {
+		keys := []string{}
+		for _, k := range m.OrderBy {
+			keys = append(keys, k.Pretty())
+		}
+		clauses = append(clauses, "order by "+strings.Join(keys, ", "))
+	}
+	if m.Limit != nil {
+		clauses = append(clauses, fmt.Sprintf("limit %d", *m.Limit))
+	}
+	if m.Offset != nil {
+		clauses = append(clauses, fmt.Sprintf("offset %d", *m.Offset))
+	}
+	if m.Scope != nil {
+		clauses = append(clauses, "match "+*m.Scope)
+	}
+	if len(m.From) > 0 {
+		clauses = append(clauses, "from "+strings.Join(m.From, ", "))
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (k *OrderKey) Pretty() string {
+	if k.Descending {
+		return k.Variable + " desc"
+	}
+	return k.Variable
+}
+
+func (a *Aggregate) Pretty() string {
+	return fmt.Sprintf("%s(%s) as %s", a.Function, a.Variable, a.As)
+}
+
+func (w *Within) Pretty() string {
+	agent := w.Agent
+	if agent == nil {
+		agent = w.AgentVar
+	}
+	return fmt.Sprintf("within (%s, %s) { %s }", *agent, w.Predicate, w.Query.Pretty())
+}
+
+func (p *PredicatePath) Pretty() string {
+	steps := []string{}
+	for _, s := range p.Alternatives {
+		steps = append(steps, s.Pretty())
+	}
+	return strings.Join(steps, "|")
+}
+
+func (s *PathStep) Pretty() string {
+	var sb strings.Builder
+	if s.Inverse {
+		sb.WriteRune('^')
+	}
+	sb.WriteString(s.Predicate)
+	if s.Modifier != nil {
+		sb.WriteString(*s.Modifier)
+	} else if s.Min != nil && s.Max != nil {
+		sb.WriteString(fmt.Sprintf("{%d,%d}", *s.Min, *s.Max))
+	} else if s.Min != nil {
+		sb.WriteString(fmt.Sprintf("{%d}", *s.Min))
+	}
+	return sb.String()
+}
+
+func (f *ObjectFilter) Pretty() string {
+	if f.Operator != nil {
+		return fmt.Sprintf("%s %s", *f.Operator, f.Value.String())
+	}
+	return fmt.Sprintf("%s..%s", FormatNumber(*f.Low), FormatNumber(*f.High))
+}
+
+func (a *Anchor) Pretty() string {
+	return fmt.Sprintf("%s = %s", a.Name, a.Fact.Pretty())
+}
+
 func (f *Fact) Pretty() string {
 	var sb strings.Builder
 	sb.WriteRune('(')
 
 	if f.Subject != nil {
 		sb.WriteString(*f.Subject)
+	} else if f.SubjectAnchor != nil {
+		sb.WriteString(*f.SubjectAnchor)
+	} else if f.SubjectID != nil {
+		sb.WriteString(f.SubjectID.String())
 	} else if f.SubjectFact != nil {
 		sb.WriteString(f.SubjectFact.Pretty())
 	}
@@ -176,6 +718,10 @@ func (f *Fact) Pretty() string {
 	sb.WriteString(", ")
 	if f.Object != nil {
 		sb.WriteString(f.Object.String())
+	} else if f.ObjectAnchor != nil {
+		sb.WriteString(*f.ObjectAnchor)
+	} else if f.ObjectID != nil {
+		sb.WriteString(f.ObjectID.String())
 	} else if f.ObjectFact != nil {
 		sb.WriteString(f.ObjectFact.Pretty())
 	}
@@ -185,6 +731,17 @@ func (f *Fact) Pretty() string {
 	return sb.String()
 }
 
+// format formats the string with its annotation.
+func (s StringObject) format() string {
+	switch {
+	case s.Lang != "":
+		return fmt.Sprintf("%q@%s", s.Value, s.Lang)
+	case s.Datatype != "":
+		return fmt.Sprintf("%q^^%s", s.Value, s.Datatype)
+	}
+	return fmt.Sprintf("%q", s.Value)
+}
+
 func (q *Query) IsLinkedCompound() bool {
 	if q.LinkedQuery == nil {
 		return false
@@ -207,8 +764,18 @@ func (q *Query) IsLinkedCompound() bool {
 
 func (f *Fact) Copy() *Fact {
 	newF := &Fact{
-		Subject:   ptrutils.PtrFromPtr(f.Subject),
-		Predicate: f.Predicate,
+		Subject:       ptrutils.PtrFromPtr(f.Subject),
+		SubjectAnchor: ptrutils.PtrFromPtr(f.SubjectAnchor),
+		Predicate:     f.Predicate,
+		ObjectAnchor:  ptrutils.PtrFromPtr(f.ObjectAnchor),
+	}
+	if f.SubjectID != nil {
+		id := *f.SubjectID
+		newF.SubjectID = &id
+	}
+	if f.ObjectID != nil {
+		id := *f.ObjectID
+		newF.ObjectID = &id
 	}
 	if f.SubjectFact != nil {
 		newF.SubjectFact = f.SubjectFact.Copy()
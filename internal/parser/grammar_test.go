@@ -1,8 +1,15 @@
 package parser
 
 import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/google/go-cmp/cmp"
 	"github.com/ozansz/semantix/pkg/ptrutils"
 )
@@ -23,6 +30,47 @@ func TestFileParser(t *testing.T) {
 			file: "../../examples/v0/0x02-queries.sxql",
 			File: queriesFile(),
 		},
+		{
+			desc: "negated queries",
+			file: "../../examples/v0/0x04-negated-queries.sxql",
+			File: &File{
+				Expressions: []*Expression{
+					{
+						Query: &Query{
+							SubjectVar:    ptrutils.Ptr("?x"),
+							Predicate:     ptrutils.Ptr("is"),
+							ObjectNegated: SubjectObject{Value: "Person"},
+							IDInFile:      "Q1",
+							Kind:          QueryKindSimple,
+						},
+					},
+					{
+						Query: &Query{
+							SubjectVar:       ptrutils.Ptr("?x"),
+							PredicateNegated: ptrutils.Ptr("knows"),
+							Object:           SubjectObject{Value: "CS"},
+							IDInFile:         "Q2",
+							Kind:             QueryKindSimple,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "topics",
+			file: "../../examples/v0/0x03-topics.sxql",
+			File: &File{
+				Expressions: []*Expression{
+					{
+						Fact: &Fact{
+							Subject:   ptrutils.Ptr("CS"),
+							Predicate: "subtopicOf",
+							Object:    SubjectObject{Value: "Science"},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -82,7 +130,7 @@ func factsFile() *File {
 				Fact: &Fact{
 					Subject:   ptrutils.Ptr("Ozan"),
 					Predicate: "age",
-					Object:    NumberObject{Value: 24},
+					Object:    IntegerObject{Value: 24},
 				},
 			},
 			{
@@ -249,3 +297,941 @@ func queriesFile() *File {
 		},
 	}
 }
+
+func TestLineParser(t *testing.T) {
+	tests := []struct {
+		desc       string
+		line       string
+		Expression *Expression
+	}{
+		{
+			desc: "delete fact",
+			line: "-(Ozan, age, 24)",
+			Expression: &Expression{
+				Delete: &Query{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: ptrutils.Ptr("age"),
+					Object:    IntegerObject{Value: 24},
+				},
+			},
+		},
+		{
+			desc: "typed literals",
+			line: "(Ozan, born, 1999-05-04)",
+			Expression: &Expression{
+				Fact: &Fact{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: "born",
+					Object:    DateObject{Value: Date{time.Date(1999, 5, 4, 0, 0, 0, 0, time.UTC)}},
+				},
+			},
+		},
+		{
+			desc: "timestamp literal",
+			line: "(Ozan, joined, 2023-07-08T14:30:00.5+03:00)",
+			Expression: &Expression{
+				Fact: &Fact{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: "joined",
+					Object:    TimestampObject{Value: time.Date(2023, 7, 8, 11, 30, 0, 5e8, time.UTC)},
+				},
+			},
+		},
+		{
+			desc: "boolean and integer literals",
+			line: "(?x, active, true) -> (?x, logins, -3)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("active"),
+					Object:     BoolObject{Value: true},
+					LinkedQuery: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("logins"),
+						Object:     IntegerObject{Value: -3},
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "language-tagged string",
+			line: `(Ozan, label, "Ozan"@EN-us)`,
+			Expression: &Expression{
+				Fact: &Fact{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: "label",
+					Object:    StringObject{Value: "Ozan", Lang: "en-us"},
+				},
+			},
+		},
+		{
+			desc: "datatyped string",
+			line: `(Ozan, code, "42"^^<http://example.org/types#code>)`,
+			Expression: &Expression{
+				Fact: &Fact{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: "code",
+					Object:    StringObject{Value: "42", Datatype: "<http://example.org/types#code>"},
+				},
+			},
+		},
+		{
+			desc: "language filter",
+			line: "(?x, label, @tr)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("label"),
+					ObjectLang: langTag("tr"),
+					IDInFile:   "Q1",
+					Kind:       QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "delete pattern",
+			line: "-(?x, knows, ?y)",
+			Expression: &Expression{
+				Delete: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("knows"),
+					ObjectVar:  ptrutils.Ptr("?y"),
+				},
+			},
+		},
+		{
+			desc: "numeric comparison",
+			line: "(?x, age, >= 20)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("age"),
+					ObjectFilter: &ObjectFilter{
+						Operator: ptrutils.Ptr(">="),
+						Value:    IntegerObject{Value: 20},
+					},
+					IDInFile: "Q1",
+					Kind:     QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "numeric range",
+			line: "(?x, length, 100..200.5)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("length"),
+					ObjectFilter: &ObjectFilter{
+						Low:  ptrutils.Ptr(100.0),
+						High: ptrutils.Ptr(200.5),
+					},
+					IDInFile: "Q1",
+					Kind:     QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "string regex",
+			line: `(?x, name, =~ "^Oz(an)?$")`,
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("name"),
+					ObjectFilter: &ObjectFilter{
+						Operator: ptrutils.Ptr("=~"),
+						Value:    StringObject{Value: "^Oz(an)?$"},
+					},
+					IDInFile: "Q1",
+					Kind:     QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "equality comparison",
+			line: "(?x, age, = 24.0)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("age"),
+					ObjectFilter: &ObjectFilter{
+						Operator: ptrutils.Ptr("="),
+						Value:    NumberObject{Value: 24},
+					},
+					IDInFile: "Q1",
+					Kind:     QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "aggregate with grouping",
+			line: "(?p, team, ?t) -> (?p, age, !a) aggregate avg(!a) as ?avgAge, count(*) as ?n group by ?t",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?p"),
+					Predicate:  ptrutils.Ptr("team"),
+					ObjectVar:  ptrutils.Ptr("?t"),
+					LinkedQuery: &Query{
+						SubjectVar: ptrutils.Ptr("?p"),
+						Predicate:  ptrutils.Ptr("age"),
+						ObjectVar:  ptrutils.Ptr("!a"),
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+				Modifiers: &Modifiers{
+					Aggregates: []*Aggregate{
+						{Function: "avg", Variable: "!a", As: "?avgAge"},
+						{Function: "count", Variable: "*", As: "?n"},
+					},
+					GroupBy: []string{"?t"},
+				},
+			},
+		},
+		{
+			desc: "order, limit and offset",
+			line: "(?x, age, ?a) order by ?a desc, ?x asc limit 10 offset 20",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("age"),
+					ObjectVar:  ptrutils.Ptr("?a"),
+					IDInFile:   "Q1",
+					Kind:       QueryKindSimple,
+				},
+				Modifiers: &Modifiers{
+					OrderBy: []*OrderKey{
+						{Variable: "?a", Descending: true},
+						{Variable: "?x"},
+					},
+					Limit:  ptrutils.Ptr(10),
+					Offset: ptrutils.Ptr(20),
+				},
+			},
+		},
+		{
+			desc: "match scope",
+			line: "(Ezgi, thinks, ?f) -> (?x, is, Person) match quoted",
+			Expression: &Expression{
+				Query: &Query{
+					Subject:   ptrutils.Ptr("Ezgi"),
+					Predicate: ptrutils.Ptr("thinks"),
+					ObjectVar: ptrutils.Ptr("?f"),
+					LinkedQuery: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("is"),
+						Object:     SubjectObject{Value: "Person"},
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+				Modifiers: &Modifiers{Scope: ptrutils.Ptr("quoted")},
+			},
+		},
+		{
+			desc: "rule",
+			line: "(?x, grandparentOf, ?z) :- (?x, parentOf, !y) -> (!y, parentOf, ?z)",
+			Expression: &Expression{
+				Rule: &Rule{
+					Head: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("grandparentOf"),
+						ObjectVar:  ptrutils.Ptr("?z"),
+					},
+					Body: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("parentOf"),
+						ObjectVar:  ptrutils.Ptr("!y"),
+						LinkedQuery: &Query{
+							SubjectVar: ptrutils.Ptr("!y"),
+							Predicate:  ptrutils.Ptr("parentOf"),
+							ObjectVar:  ptrutils.Ptr("?z"),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "transitive path",
+			line: "(CS, subtopicOf+, ?x)",
+			Expression: &Expression{
+				Query: &Query{
+					Subject: ptrutils.Ptr("CS"),
+					PredicatePath: &PredicatePath{
+						Alternatives: []*PathStep{
+							{Predicate: "subtopicOf", Modifier: ptrutils.Ptr("+")},
+						},
+					},
+					ObjectVar: ptrutils.Ptr("?x"),
+					IDInFile:  "Q1",
+					Kind:      QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "path alternatives",
+			line: "(?x, knows|^likes|subtopicOf{1,3}|partOf{2}, ?y)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					PredicatePath: &PredicatePath{
+						Alternatives: []*PathStep{
+							{Predicate: "knows"},
+							{Predicate: "likes", Inverse: true},
+							{Predicate: "subtopicOf", Min: ptrutils.Ptr(1), Max: ptrutils.Ptr(3)},
+							{Predicate: "partOf", Min: ptrutils.Ptr(2)},
+						},
+					},
+					ObjectVar: ptrutils.Ptr("?y"),
+					IDInFile:  "Q1",
+					Kind:      QueryKindSimple,
+				},
+			},
+		},
+		{
+			desc: "optional pattern",
+			line: "(?x, is, Person) -> optional (?x, email, ?e)",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("is"),
+					Object:     SubjectObject{Value: "Person"},
+					LinkedQuery: &Query{
+						Optional:   true,
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("email"),
+						ObjectVar:  ptrutils.Ptr("?e"),
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "union",
+			line: "{ (?x, knows, CS) | (?x, knows, !t) -> (!t, subtopicOf, CS) } -> (?x, age, ?a)",
+			Expression: &Expression{
+				Query: &Query{
+					Union: []*Query{
+						{
+							SubjectVar: ptrutils.Ptr("?x"),
+							Predicate:  ptrutils.Ptr("knows"),
+							Object:     SubjectObject{Value: "CS"},
+						},
+						{
+							SubjectVar: ptrutils.Ptr("?x"),
+							Predicate:  ptrutils.Ptr("knows"),
+							ObjectVar:  ptrutils.Ptr("!t"),
+							LinkedQuery: &Query{
+								SubjectVar: ptrutils.Ptr("!t"),
+								Predicate:  ptrutils.Ptr("subtopicOf"),
+								Object:     SubjectObject{Value: "CS"},
+							},
+						},
+					},
+					LinkedQuery: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("age"),
+						ObjectVar:  ptrutils.Ptr("?a"),
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "exists and not exists",
+			line: "(?x, is, Person) -> exists { (?x, knows, CS) } -> not exists { (?x, approvedBy, !y) }",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("is"),
+					Object:     SubjectObject{Value: "Person"},
+					LinkedQuery: &Query{
+						Exists: &Query{
+							SubjectVar: ptrutils.Ptr("?x"),
+							Predicate:  ptrutils.Ptr("knows"),
+							Object:     SubjectObject{Value: "CS"},
+						},
+						LinkedQuery: &Query{
+							NotExists: &Query{
+								SubjectVar: ptrutils.Ptr("?x"),
+								Predicate:  ptrutils.Ptr("approvedBy"),
+								ObjectVar:  ptrutils.Ptr("!y"),
+							},
+						},
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "filter and bind",
+			line: "(?x, age, ?a) -> filter not ?a < 18 -> bind (?a + 1) * 12 as ?m",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("age"),
+					ObjectVar:  ptrutils.Ptr("?a"),
+					LinkedQuery: &Query{
+						Filter: &Expr{Or: []*Conjunction{{And: []*Negation{{
+							Not: true,
+							Comparison: &Comparison{
+								Left:     &Sum{Left: &Product{Left: &Unary{Operand: &Primary{Variable: ptrutils.Ptr("?a")}}}},
+								Operator: ptrutils.Ptr("<"),
+								Right:    &Sum{Left: &Product{Left: &Unary{Operand: &Primary{Integer: ptrutils.Ptr(int64(18))}}}},
+							},
+						}}}}},
+						LinkedQuery: &Query{
+							Bind: &Bind{
+								Expr: &Expr{Or: []*Conjunction{{And: []*Negation{{
+									Comparison: &Comparison{Left: &Sum{Left: &Product{
+										Left: &Unary{Operand: &Primary{Sub: &Expr{Or: []*Conjunction{{And: []*Negation{{
+											Comparison: &Comparison{Left: &Sum{
+												Left: &Product{Left: &Unary{Operand: &Primary{Variable: ptrutils.Ptr("?a")}}},
+												Rest: []*SumOp{{Operator: "+", Operand: &Product{Left: &Unary{Operand: &Primary{Integer: ptrutils.Ptr(int64(1))}}}}},
+											}},
+										}}}}}}},
+										Rest: []*ProductOp{{Operator: "*", Operand: &Unary{Operand: &Primary{Integer: ptrutils.Ptr(int64(12))}}}},
+									}}},
+								}}}}},
+								Variable: "?m",
+							},
+						},
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "within",
+			line: "(?a, is, Person) -> within (?a, thinks) { (?x, is, Person) }",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?a"),
+					Predicate:  ptrutils.Ptr("is"),
+					Object:     SubjectObject{Value: "Person"},
+					LinkedQuery: &Query{
+						Within: &Within{
+							AgentVar:  ptrutils.Ptr("?a"),
+							Predicate: "thinks",
+							Query: &Query{
+								SubjectVar: ptrutils.Ptr("?x"),
+								Predicate:  ptrutils.Ptr("is"),
+								Object:     SubjectObject{Value: "Person"},
+							},
+						},
+					},
+					IDInFile: "LQ1",
+					Kind:     QueryKindLinked,
+				},
+			},
+		},
+		{
+			desc: "construct",
+			line: "construct (?x, is, Engineer) where (?x, knows, CS) limit 1",
+			Expression: &Expression{
+				Construct: &Construct{
+					Templates: []*Query{{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("is"),
+						Object:     SubjectObject{Value: "Engineer"},
+					}},
+					Where: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("knows"),
+						Object:     SubjectObject{Value: "CS"},
+					},
+					Modifiers: &Modifiers{Limit: ptrutils.Ptr(1)},
+				},
+			},
+		},
+		{
+			desc: "insert with nested templates",
+			line: "insert { (?x, is, Engineer), (METU, certifies, (?x, knows, CS)) } where (?x, knows, CS)",
+			Expression: &Expression{
+				Construct: &Construct{
+					Insert: true,
+					Templates: []*Query{
+						{
+							SubjectVar: ptrutils.Ptr("?x"),
+							Predicate:  ptrutils.Ptr("is"),
+							Object:     SubjectObject{Value: "Engineer"},
+						},
+						{
+							Subject:   ptrutils.Ptr("METU"),
+							Predicate: ptrutils.Ptr("certifies"),
+							ObjectQuery: &Query{
+								SubjectVar: ptrutils.Ptr("?x"),
+								Predicate:  ptrutils.Ptr("knows"),
+								Object:     SubjectObject{Value: "CS"},
+							},
+						},
+					},
+					Where: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("knows"),
+						Object:     SubjectObject{Value: "CS"},
+					},
+				},
+			},
+		},
+		{
+			desc: "update",
+			line: "update (?x, knows, ?y) set (?x, isFamiliarWith, ?y)",
+			Expression: &Expression{
+				Update: &Update{
+					Match: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("knows"),
+						ObjectVar:  ptrutils.Ptr("?y"),
+					},
+					Set: &Query{
+						SubjectVar: ptrutils.Ptr("?x"),
+						Predicate:  ptrutils.Ptr("isFamiliarWith"),
+						ObjectVar:  ptrutils.Ptr("?y"),
+					},
+				},
+			},
+		},
+		{
+			desc: "use",
+			line: "use HR",
+			Expression: &Expression{
+				Use: &Use{Graph: "HR"},
+			},
+		},
+		{
+			desc: "drop graph",
+			line: "drop graph HR",
+			Expression: &Expression{
+				Drop: &DropGraph{Graph: "HR"},
+			},
+		},
+		{
+			desc: "reload graph",
+			line: `reload graph HR from "hr.sxql"`,
+			Expression: &Expression{
+				Reload: &Reload{Graph: "HR", Path: "hr.sxql"},
+			},
+		},
+		{
+			desc: "from graphs",
+			line: "(?x, is, Person) limit 1 from HR, default",
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?x"),
+					Predicate:  ptrutils.Ptr("is"),
+					Object:     SubjectObject{Value: "Person"},
+					IDInFile:   "Q1",
+					Kind:       QueryKindSimple,
+				},
+				Modifiers: &Modifiers{Limit: ptrutils.Ptr(1), From: []string{"HR", "default"}},
+			},
+		},
+		{
+			desc: "describe",
+			line: "describe Ozan depth 2",
+			Expression: &Expression{
+				Describe: &Describe{Subject: "Ozan", Depth: ptrutils.Ptr(2)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := New()
+			e, err := parser.ParseLine(tc.line)
+			if err != nil {
+				t.Fatalf("failed to parse line: %v", err)
+			}
+			if diff := cmp.Diff(tc.Expression, e); diff != "" {
+				t.Errorf("unexpected expression (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		desc       string
+		lines      []string
+		Expression *Expression
+		wantError  bool
+	}{
+		{
+			desc:  "definition",
+			lines: []string{`_f1 = (Ozan, knows, Go)`},
+			Expression: &Expression{
+				Anchor: &Anchor{
+					Name: "_f1",
+					Fact: &Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+				},
+			},
+		},
+		{
+			desc:  "fact referencing anchors",
+			lines: []string{`_f1 = (Ozan, knows, Go)`, `(Ali, believes, _f1)`},
+			Expression: &Expression{
+				Fact: &Fact{
+					Subject:    ptrutils.Ptr("Ali"),
+					Predicate:  "believes",
+					ObjectFact: &Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+				},
+			},
+		},
+		{
+			desc:  "query referencing anchors",
+			lines: []string{`_f1 = (Ozan, knows, Go)`, `(?who, believes, _f1)`},
+			Expression: &Expression{
+				Query: &Query{
+					SubjectVar: ptrutils.Ptr("?who"),
+					Predicate:  ptrutils.Ptr("believes"),
+					ObjectQuery: &Query{
+						Subject:   ptrutils.Ptr("Ozan"),
+						Predicate: ptrutils.Ptr("knows"),
+						Object:    SubjectObject{Value: "Go"},
+					},
+					IDInFile: "CQ1",
+					Kind:     QueryKindCompound,
+				},
+			},
+		},
+		{
+			desc:      "undefined anchor",
+			lines:     []string{`(Ali, believes, _f1)`},
+			wantError: true,
+		},
+		{
+			desc:  "fact by ID",
+			lines: []string{`(#0000000042, approvedBy, METU)`},
+			Expression: &Expression{
+				Fact: &Fact{
+					SubjectFact: &Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+					Predicate:   "approvedBy",
+					Object:      SubjectObject{Value: "METU"},
+				},
+			},
+		},
+		{
+			desc:  "delete by ID",
+			lines: []string{`- #0000000042`},
+			Expression: &Expression{
+				Delete: &Query{
+					Subject:   ptrutils.Ptr("Ozan"),
+					Predicate: ptrutils.Ptr("knows"),
+					Object:    SubjectObject{Value: "Go"},
+				},
+			},
+		},
+		{
+			desc:  "describe by ID",
+			lines: []string{`describe #0000000042`},
+			Expression: &Expression{
+				Describe: &Describe{
+					ID:   (*FactID)(ptrutils.Ptr(uint32(42))),
+					Fact: &Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+				},
+			},
+		},
+		{
+			desc:  "comment starting with a number",
+			lines: []string{`(Ozan, knows, Go) #1 fact of the file`},
+			Expression: &Expression{
+				Fact: &Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+			},
+		},
+		{
+			desc:      "short ID",
+			lines:     []string{`- #42`},
+			wantError: true,
+		},
+		{
+			desc:      "unknown ID",
+			lines:     []string{`(Ali, believes, #0000000007)`},
+			wantError: true,
+		},
+	}
+	facts := map[uint32]*Fact{
+		42: {Subject: ptrutils.Ptr("Ozan"), Predicate: "knows", Object: SubjectObject{Value: "Go"}},
+	}
+	lookup := func(id uint32) (*Fact, error) {
+		f, ok := facts[id]
+		if !ok {
+			return nil, fmt.Errorf("not found")
+		}
+		return f.Copy(), nil
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := New()
+			var e *Expression
+			var err error
+			for _, l := range tc.lines {
+				if e, err = parser.ParseLine(l); err != nil {
+					break
+				}
+				if err = ResolveFactIDs(e, lookup); err != nil {
+					break
+				}
+			}
+			if gotError := err != nil; gotError != tc.wantError {
+				t.Fatalf("parser.ParseLine() error = %v, want error %v", err, tc.wantError)
+			}
+			if tc.wantError {
+				return
+			}
+			if diff := cmp.Diff(tc.Expression, e); diff != "" {
+				t.Errorf("unexpected expression (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	tests := []struct {
+		desc      string
+		lines     []string
+		file      bool
+		want      string
+		wantFact  *Fact
+		wantError bool
+	}{
+		{
+			desc:  "prefixed names",
+			lines: []string{"@prefix hr: <http://example.org/hr#>", "(hr:Ozan, hr:worksIn, hr:Sales)"},
+			want:  "(hr:Ozan, hr:worksIn, hr:Sales)",
+			wantFact: &Fact{
+				Subject:   ptrutils.Ptr("<http://example.org/hr#Ozan>"),
+				Predicate: "<http://example.org/hr#worksIn>",
+				Object:    SubjectObject{Value: "<http://example.org/hr#Sales>"},
+			},
+		},
+		{
+			desc:  "IRIs",
+			lines: []string{"@prefix crm: <http://example.org/crm/>", "(<http://example.org/crm/Ozan>, is, <http://example.org/other#Person>)"},
+			want:  "(crm:Ozan, is, <http://example.org/other#Person>)",
+			wantFact: &Fact{
+				Subject:   ptrutils.Ptr("<http://example.org/crm/Ozan>"),
+				Predicate: "is",
+				Object:    SubjectObject{Value: "<http://example.org/other#Person>"},
+			},
+		},
+		{
+			desc: "longest namespace",
+			lines: []string{
+				"@prefix ex: <http://example.org/>",
+				"@prefix exp: <http://example.org/people/>",
+				"(<http://example.org/people/Ozan>, is, ex:Person)",
+			},
+			want: "(exp:Ozan, is, ex:Person)",
+			wantFact: &Fact{
+				Subject:   ptrutils.Ptr("<http://example.org/people/Ozan>"),
+				Predicate: "is",
+				Object:    SubjectObject{Value: "<http://example.org/Person>"},
+			},
+		},
+		{
+			desc:      "prefix used before its declaration",
+			lines:     []string{"(doc:Ozan, is, doc:Author)", "@prefix doc: <http://example.org/doc#>"},
+			file:      true,
+			wantError: true,
+		},
+		{
+			desc:      "undefined prefix",
+			lines:     []string{"(nope:Ozan, is, Person)"},
+			wantError: true,
+		},
+		{
+			desc:      "namespace that is not an IRI",
+			lines:     []string{"@prefix bad: Person"},
+			wantError: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			parser := New()
+			var e *Expression
+			var err error
+			if tc.file {
+				path := filepath.Join(t.TempDir(), "prefixes.sxql")
+				if err := os.WriteFile(path, []byte(strings.Join(tc.lines, "\n")+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				var f *File
+				if f, err = parser.ParseFile(path); err == nil {
+					e = f.Expressions[len(f.Expressions)-1]
+				}
+			} else {
+				for _, l := range tc.lines {
+					if e, err = parser.ParseLine(l); err != nil {
+						break
+					}
+				}
+			}
+			if gotError := err != nil; gotError != tc.wantError {
+				t.Fatalf("parser.ParseLine() error = %v, want error %v", err, tc.wantError)
+			}
+			if tc.wantError {
+				return
+			}
+			if diff := cmp.Diff(tc.wantFact, e.Fact); diff != "" {
+				t.Errorf("unexpected fact (-want +got):\n%s", diff)
+			}
+			if got := parser.Compact(e.Fact.Pretty()); got != tc.want {
+				t.Errorf("parser.Compact(e.Fact.Pretty()) = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilePrefixes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]string{
+		"hr.sxql": {
+			"@prefix ex: <http://example.org/hr#>",
+			"(ex:Ozan, ex:worksIn, ex:Sales)",
+			"@prefix ex: <http://example.org/people#>",
+			"(ex:Ozan, is, Person)",
+		},
+		"crm.sxql": {
+			"@prefix ex: <http://example.org/crm#>",
+			"(ex:Ozan, ex:buys, \"<http://example.org/crm#Car>\")",
+		},
+		"plain.sxql": {
+			"(ex:Ozan, is, Person)",
+		},
+	}
+	for name, lines := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parse := func(p *Parser, name string) []string {
+		t.Helper()
+		f, err := p.ParseFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("parser.ParseFile(%s) error = %v", name, err)
+		}
+		got := []string{}
+		for _, e := range f.Expressions {
+			if e.Fact != nil {
+				got = append(got, e.Fact.Pretty())
+			}
+		}
+		return got
+	}
+
+	p := New()
+	if _, err := p.ParseLine("@prefix crm: <http://example.org/crm#>"); err != nil {
+		t.Fatalf("parser.ParseLine() error = %v", err)
+	}
+	want := []string{
+		"(<http://example.org/hr#Ozan>, <http://example.org/hr#worksIn>, <http://example.org/hr#Sales>)",
+		"(<http://example.org/people#Ozan>, is, Person)",
+	}
+	if diff := cmp.Diff(want, parse(p, "hr.sxql")); diff != "" {
+		t.Errorf("unexpected facts of hr.sxql (-want +got):\n%s", diff)
+	}
+	want = []string{`(<http://example.org/crm#Ozan>, <http://example.org/crm#buys>, "<http://example.org/crm#Car>")`}
+	got := parse(p, "crm.sxql")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected facts of crm.sxql (-want +got):\n%s", diff)
+	}
+	// The prefixes of the files are not in effect after them, only the ones
+	// declared to the parser.
+	if _, err := p.ParseFile(filepath.Join(dir, "plain.sxql")); err == nil {
+		t.Errorf("parser.ParseFile(plain.sxql) succeeded with a prefix of another file")
+	}
+	if got, want := p.Compact(got[0]), `(crm:Ozan, crm:buys, "<http://example.org/crm#Car>")`; got != want {
+		t.Errorf("parser.Compact() = %s, want %s", got, want)
+	}
+	if compacted := New().Compact(got[0]); compacted != got[0] {
+		t.Errorf("parser.Compact() of a new parser = %s, want %s", compacted, got[0])
+	}
+}
+
+func langTag(tag string) *LangTag {
+	l := LangTag(tag)
+	return &l
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{value: 0, want: "0.0"},
+		{value: 24, want: "24.0"},
+		{value: -0.5, want: "-0.5"},
+		{value: 123.4567891, want: "123.4567891"},
+		{value: 1e-9, want: "1e-09"},
+		{value: 1e20, want: "100000000000000000000.0"},
+		{value: 1e21, want: "1e+21"},
+		{value: math.MaxFloat64, want: "1.7976931348623157e+308"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.want, func(t *testing.T) {
+			got := FormatNumber(tc.value)
+			if got != tc.want {
+				t.Fatalf("FormatNumber(%v) = %s, want %s", tc.value, got, tc.want)
+			}
+			e, err := New().ParseLine(fmt.Sprintf("(x, value, %s)", got))
+			if err != nil {
+				t.Fatalf("parser.ParseLine() error = %v", err)
+			}
+			if diff := cmp.Diff(NumberObject{Value: tc.value}, e.Fact.Object); diff != "" {
+				t.Errorf("unexpected object (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLexFactIDs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "#0123456789", want: []string{"FactID"}},
+		{text: "(Ali, believes, #0123456789)", want: []string{"Punct", "Ident", "Punct", "Ident", "Punct", "FactID", "Punct"}},
+		{text: "#0123456789 note", want: []string{"FactID", "Ident"}},
+		{text: "# 0123456789 note", want: []string{"Comment"}},
+		{text: "-- 0123456789 note", want: []string{"Comment"}},
+		{text: "#1 note", want: []string{"Comment"}},
+		{text: "#01234567890 note", want: []string{"Comment"}},
+		{text: "(Ozan, knows, Go) # 0123456789", want: []string{"Punct", "Ident", "Punct", "Ident", "Punct", "Ident", "Punct", "Comment"}},
+	}
+	names := map[lexer.TokenType]string{}
+	for name, typ := range sxQLLexer.Symbols() {
+		names[typ] = name
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.text, func(t *testing.T) {
+			l, err := sxQLLexer.Lex("", strings.NewReader(tc.text))
+			if err != nil {
+				t.Fatalf("sxQLLexer.Lex() error = %v", err)
+			}
+			tokens, err := lexer.ConsumeAll(l)
+			if err != nil {
+				t.Fatalf("lexer.ConsumeAll() error = %v", err)
+			}
+			got := []string{}
+			for _, tok := range tokens {
+				if name := names[tok.Type]; !tok.EOF() && name != "Whitespace" {
+					got = append(got, name)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected tokens (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
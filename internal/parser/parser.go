@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -10,39 +11,72 @@ import (
 
 var (
 	sxQLLexer = lexer.MustSimple([]lexer.SimpleRule{
+		// Fact IDs take precedence over comments, see FactID.
+		{Name: "FactID", Pattern: `#\d{10}\b`},
 		{Name: "Comment", Pattern: `(?:#|--)[^\n]*\n?`},
 		{Name: `QueryIdent`, Pattern: `[?!][a-zA-Z][a-zA-Z_\d]*`},
-		{Name: `Ident`, Pattern: `[a-zA-Z][a-zA-Z_\d]*`},
-		// {Name: `AnchorIdent`, Pattern: `_[a-zA-Z_\d]+`},
+		{Name: `Ident`, Pattern: `<[a-zA-Z][a-zA-Z\d+.-]*:[^\s<>"]*>|[a-zA-Z][a-zA-Z_\d]*(?::[a-zA-Z_\d]+)?`},
+		{Name: `AnchorIdent`, Pattern: `_[a-zA-Z_\d]+`},
+		{Name: `LangTag`, Pattern: `@[a-zA-Z]+(?:-[a-zA-Z\d]+)*`},
 		{Name: `String`, Pattern: `"(?:\\.|[^"])*"`},
-		{Name: `Number`, Pattern: `[-+]?\d*\.?\d+([eE][-+]?\d+)?`},
-		{Name: `Punct`, Pattern: `[-[!@#$%^&*()+_={}\|:;"'<,>.?/]|]`},
+		{Name: `Timestamp`, Pattern: `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[-+]\d{2}:\d{2})`},
+		{Name: `Date`, Pattern: `\d{4}-\d{2}-\d{2}\b`},
+		{Name: `Range`, Pattern: `\.\.`},
+		{Name: `Number`, Pattern: `[-+]?(?:\d*\.\d+(?:[eE][-+]?\d+)?|\d+[eE][-+]?\d+)`},
+		{Name: `Int`, Pattern: `[-+]?\d+`},
+		{Name: `Punct`, Pattern: `[-[!@#$%^&*()+_={}\|:;"'<,>.?/~]|]`},
 		{Name: `Whitespace`, Pattern: `[ \t\n\r]+`},
 	})
 )
 
 type Parser struct {
 	expParser  *participle.Parser[Expression]
-	fileParser *participle.Parser[File]
+	fileParser *participle.Parser[fileSpans]
+	// anchors holds the facts labelled by the anchors parsed so far.
+	anchors map[string]*Fact
+	// prefixes holds the prefixes declared to the parser so far, or to the
+	// file being parsed.
+	prefixes prefixes
+	// collecting is set while a file is parsed to find its expressions only,
+	// leaving the prefixed names it can not expand yet.
+	collecting bool
+}
+
+// fileSpans is a file as read to find its expressions, which are parsed
+// again one by one, in order.
+type fileSpans struct {
+	Expressions []*expressionSpan `@@*`
+}
+
+// expressionSpan is an expression along with where it is in its file.
+type expressionSpan struct {
+	Pos        lexer.Position
+	EndPos     lexer.Position
+	Expression *Expression `@@`
 }
 
 func New() *Parser {
-	return &Parser{
-		expParser: participle.MustBuild[Expression](
-			participle.Lexer(sxQLLexer),
-			participle.Unquote("String"),
-			participle.Union[Object](SubjectObject{}, StringObject{}, NumberObject{}), //, RelationAnchorObject{}),
-			participle.Elide("Comment", "Whitespace"),
-			participle.UseLookahead(5),
-		),
-		fileParser: participle.MustBuild[File](
-			participle.Lexer(sxQLLexer),
-			participle.Unquote("String"),
-			participle.Union[Object](SubjectObject{}, StringObject{}, NumberObject{}), //, RelationAnchorObject{}),
-			participle.Elide("Comment", "Whitespace"),
-			participle.UseLookahead(5),
-		),
+	p := &Parser{
+		anchors:  map[string]*Fact{},
+		prefixes: prefixes{},
 	}
+	p.expParser = participle.MustBuild[Expression](
+		participle.Lexer(sxQLLexer),
+		participle.Unquote("String"),
+		participle.Map(p.expandPrefixedName, "Ident"),
+		participle.Union[Object](BoolObject{}, SubjectObject{}, StringObject{}, TimestampObject{}, DateObject{}, NumberObject{}, IntegerObject{}), //, RelationAnchorObject{}),
+		participle.Elide("Comment", "Whitespace"),
+		participle.UseLookahead(participle.MaxLookahead),
+	)
+	p.fileParser = participle.MustBuild[fileSpans](
+		participle.Lexer(sxQLLexer),
+		participle.Unquote("String"),
+		participle.Map(p.expandPrefixedName, "Ident"),
+		participle.Union[Object](BoolObject{}, SubjectObject{}, StringObject{}, TimestampObject{}, DateObject{}, NumberObject{}, IntegerObject{}), //, RelationAnchorObject{}),
+		participle.Elide("Comment", "Whitespace"),
+		participle.UseLookahead(participle.MaxLookahead),
+	)
+	return p
 }
 
 func (p *Parser) ParseLine(input string) (*Expression, error) {
@@ -50,21 +84,46 @@ func (p *Parser) ParseLine(input string) (*Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := p.resolveReferences(exp); err != nil {
+		return nil, err
+	}
 	exprs := []*Expression{exp}
 	p.postProcessQueries(exprs)
 	return exprs[0], nil
 }
 
+// ParseFile parses the expressions of the file. The prefixes the file declares
+// are in effect from their declaration to the end of the file, so the file is
+// read once to find its expressions, which are then parsed one by one.
 func (p *Parser) ParseFile(path string) (*File, error) {
-	f, err := os.Open(path)
-	defer f.Close()
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	file, err := p.fileParser.Parse(path, f)
+	outer := p.prefixes
+	p.prefixes = outer.copy()
+	defer func() { p.prefixes = outer }()
+
+	p.collecting = true
+	spans, err := p.fileParser.ParseBytes(path, b)
+	p.collecting = false
 	if err != nil {
 		return nil, err
 	}
+	file := &File{}
+	for _, s := range spans.Expressions {
+		e, err := p.expParser.ParseBytes(path, b[s.Pos.Offset:s.EndPos.Offset])
+		if err != nil {
+			if perr, ok := err.(participle.Error); ok {
+				err = errors.New(perr.Message())
+			}
+			return nil, fmt.Errorf("%s: %v", s.Pos, err)
+		}
+		if err := p.resolveReferences(e); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", s.Pos, e.Pretty(), err)
+		}
+		file.Expressions = append(file.Expressions, e)
+	}
 	p.postProcessQueries(file.Expressions)
 	return file, nil
 }
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var (
+	localNameRe = regexp.MustCompile(`^[a-zA-Z_\d]+$`)
+	// iriRe matches an IRI at the start of a text, as the lexer reads it.
+	iriRe = regexp.MustCompile(`^<[a-zA-Z][a-zA-Z\d+.-]*:[^\s<>"]*>`)
+)
+
+// Prefix declares a prefix abbreviating a namespace, e.g.
+// @prefix hr: <http://example.org/hr#>. Prefixed names, like hr:Person, are
+// expanded into the IRIs they abbreviate, <http://example.org/hr#Person>.
+type Prefix struct {
+	Name      string `"@prefix" @Ident ":"`
+	Namespace string `@Ident`
+}
+
+func (p *Prefix) Pretty() string {
+	return fmt.Sprintf("@prefix %s: %s", p.Name, p.Namespace)
+}
+
+// prefixes maps the declared prefixes to the namespaces they abbreviate.
+type prefixes map[string]string
+
+// declare makes the prefix abbreviate the namespace, an IRI. Declaring a
+// prefix again replaces its namespace.
+func (ps prefixes) declare(prefix, namespace string) error {
+	if !isIRI(namespace) {
+		return fmt.Errorf("namespace of prefix %s must be an IRI, got %s", prefix, namespace)
+	}
+	ps[prefix] = namespace[1 : len(namespace)-1]
+	return nil
+}
+
+func (ps prefixes) copy() prefixes {
+	c := prefixes{}
+	for prefix, namespace := range ps {
+		c[prefix] = namespace
+	}
+	return c
+}
+
+// compact returns the identifier with its namespace abbreviated by the prefix
+// with the longest namespace, or the identifier itself if no prefix
+// abbreviates it.
+func (ps prefixes) compact(ident string) string {
+	if !isIRI(ident) {
+		return ident
+	}
+	iri := ident[1 : len(ident)-1]
+	best, bestNamespace := "", ""
+	for prefix, namespace := range ps {
+		if !strings.HasPrefix(iri, namespace) || !localNameRe.MatchString(iri[len(namespace):]) {
+			continue
+		}
+		if len(namespace) > len(bestNamespace) || (len(namespace) == len(bestNamespace) && prefix < best) {
+			best, bestNamespace = prefix, namespace
+		}
+	}
+	if bestNamespace == "" {
+		return ident
+	}
+	return best + ":" + iri[len(bestNamespace):]
+}
+
+// Compact returns the text, e.g. the Pretty form of a fact, with the IRIs it
+// mentions abbreviated by the prefixes declared to the parser. IRIs quoted in
+// strings are left as they are.
+func (p *Parser) Compact(text string) string {
+	var sb strings.Builder
+	quoted, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '<':
+			if iri := iriRe.FindString(text[i:]); iri != "" {
+				sb.WriteString(p.prefixes.compact(iri))
+				i += len(iri) - 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// expandPrefixedName maps the prefixed names among the identifier tokens to
+// the IRIs they abbreviate. Undefined prefixes are an error, unless the parser
+// is only collecting the expressions of a file.
+func (p *Parser) expandPrefixedName(t lexer.Token) (lexer.Token, error) {
+	if isIRI(t.Value) {
+		return t, nil
+	}
+	prefix, local, ok := strings.Cut(t.Value, ":")
+	if !ok {
+		return t, nil
+	}
+	namespace, ok := p.prefixes[prefix]
+	if !ok {
+		if p.collecting {
+			return t, nil
+		}
+		return t, fmt.Errorf("undefined prefix: %s", prefix)
+	}
+	t.Value = "<" + namespace + local + ">"
+	return t, nil
+}
+
+func isIRI(ident string) bool {
+	return strings.HasPrefix(ident, "<") && strings.HasSuffix(ident, ">")
+}
@@ -0,0 +1,23 @@
+package parser
+
+import "fmt"
+
+// FactLookup returns the fact with the given ID.
+type FactLookup func(id uint32) (*Fact, error)
+
+// ResolveFactIDs replaces the fact IDs referenced by the expression with the
+// facts the lookup returns for them. It is called right before the expression
+// is executed, so that the expression can reference the facts added by the
+// ones executed before it.
+func ResolveFactIDs(e *Expression, lookup FactLookup) error {
+	return resolveExpression(e, func(_ *string, id *FactID) (*Fact, error) {
+		if id == nil {
+			return nil, nil
+		}
+		f, err := lookup(uint32(*id))
+		if err != nil {
+			return nil, fmt.Errorf("fact %s: %v", id, err)
+		}
+		return f, nil
+	})
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type ComparisonOperator string
+
+const (
+	OperatorEqual        ComparisonOperator = "="
+	OperatorLess         ComparisonOperator = "<"
+	OperatorLessEqual    ComparisonOperator = "<="
+	OperatorGreater      ComparisonOperator = ">"
+	OperatorGreaterEqual ComparisonOperator = ">="
+	OperatorNotEqual     ComparisonOperator = "!="
+	OperatorPrefix       ComparisonOperator = "^="
+	OperatorSuffix       ComparisonOperator = "$="
+	OperatorContains     ComparisonOperator = "*="
+	OperatorRegex        ComparisonOperator = "=~"
+)
+
+// Comparison constrains a value by comparing it against a constant.
+type Comparison struct {
+	Operator ComparisonOperator
+	Value    *Object
+	re       *regexp.Regexp
+}
+
+func NewComparison(op ComparisonOperator, value *Object) (*Comparison, error) {
+	c := &Comparison{
+		Operator: op,
+		Value:    value,
+	}
+	switch op {
+	case OperatorEqual, OperatorLess, OperatorLessEqual, OperatorGreater, OperatorGreaterEqual, OperatorNotEqual:
+	case OperatorPrefix, OperatorSuffix, OperatorContains, OperatorRegex:
+		if value.Kind != ObjectKindString {
+			return nil, fmt.Errorf("operator %s expects a string, got %s", op, value.String())
+		}
+		if op == OperatorRegex {
+			re, err := regexp.Compile(*value.StringValue)
+			if err != nil {
+				return nil, fmt.Errorf("regexp.Compile: %v", err)
+			}
+			c.re = re
+		}
+	default:
+		return nil, fmt.Errorf("unknown comparison operator: %s", op)
+	}
+	return c, nil
+}
+
+// Holds reports whether the comparison holds for the given value. Integers and
+// floats are compared numerically with each other, dates and timestamps
+// chronologically, subjects and strings lexically; ordering comparisons
+// between values of other kinds never hold.
+func (c *Comparison) Holds(o *Object) bool {
+	switch c.Operator {
+	case OperatorEqual:
+		return equalValues(o, c.Value)
+	case OperatorNotEqual:
+		return !equalValues(o, c.Value)
+	case OperatorPrefix, OperatorSuffix, OperatorContains, OperatorRegex:
+		if !isStringKind(o.Kind) {
+			return false
+		}
+		s, sub := *o.StringValue, *c.Value.StringValue
+		switch c.Operator {
+		case OperatorPrefix:
+			return strings.HasPrefix(s, sub)
+		case OperatorSuffix:
+			return strings.HasSuffix(s, sub)
+		case OperatorContains:
+			return strings.Contains(s, sub)
+		default:
+			return c.re.MatchString(s)
+		}
+	}
+	cmp, ok := compareObjects(o, c.Value)
+	if !ok {
+		return false
+	}
+	switch c.Operator {
+	case OperatorLess:
+		return cmp < 0
+	case OperatorLessEqual:
+		return cmp <= 0
+	case OperatorGreater:
+		return cmp > 0
+	case OperatorGreaterEqual:
+		return cmp >= 0
+	}
+	return false
+}
+
+func (c *Comparison) Pretty() string {
+	return fmt.Sprintf("%s %s", c.Operator, c.Value.String())
+}
+
+// equalValues reports whether the values are equal, comparing numbers by value
+// whether they are integers or floats.
+func equalValues(a, b *Object) bool {
+	if isNumberKind(a.Kind) && isNumberKind(b.Kind) {
+		cmp, _ := compareObjects(a, b)
+		return cmp == 0
+	}
+	return a.Equal(b)
+}
+
+// compareObjects orders two values of comparable kinds. It returns false if
+// the values are not comparable.
+func compareObjects(a, b *Object) (int, bool) {
+	switch {
+	case a.Kind == ObjectKindInt && b.Kind == ObjectKindInt:
+		switch {
+		case *a.IntValue < *b.IntValue:
+			return -1, true
+		case *a.IntValue > *b.IntValue:
+			return 1, true
+		}
+		return 0, true
+	case isNumberKind(a.Kind) && isNumberKind(b.Kind):
+		x, y := floatValue(a), floatValue(b)
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case isTimeKind(a.Kind) && isTimeKind(b.Kind):
+		switch {
+		case a.TimeValue.Before(*b.TimeValue):
+			return -1, true
+		case a.TimeValue.After(*b.TimeValue):
+			return 1, true
+		}
+		return 0, true
+	case isStringKind(a.Kind) && isStringKind(b.Kind):
+		return strings.Compare(*a.StringValue, *b.StringValue), true
+	case a.Kind == ObjectKindBool && b.Kind == ObjectKindBool:
+		switch {
+		case *a.BoolValue == *b.BoolValue:
+			return 0, true
+		case *b.BoolValue:
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func isStringKind(k ObjectKind) bool {
+	return k == ObjectKindSubject || k == ObjectKindString
+}
+
+func isNumberKind(k ObjectKind) bool {
+	return k == ObjectKindFloat || k == ObjectKindInt
+}
+
+// isTimeKind reports whether the values of the kind are instants. Dates are
+// midnight UTC of their day.
+func isTimeKind(k ObjectKind) bool {
+	return k == ObjectKindDate || k == ObjectKindTimestamp
+}
+
+// orderObjects totally orders values for sorting solutions: unbound values
+// come first, then numbers, subjects and strings, booleans, dates and
+// timestamps, and facts. Numbers are ordered numerically, booleans false
+// first, dates and timestamps chronologically, the rest lexically.
+func orderObjects(a, b *Object) int {
+	rank := func(o *Object) int {
+		switch {
+		case o == nil:
+			return 0
+		case isNumberKind(o.Kind):
+			return 1
+		case isStringKind(o.Kind):
+			return 2
+		case o.Kind == ObjectKindBool:
+			return 3
+		case isTimeKind(o.Kind):
+			return 4
+		}
+		return 5
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	if a == nil {
+		return 0
+	}
+	if cmp, ok := compareObjects(a, b); ok {
+		return cmp
+	}
+	return strings.Compare(a.String(), b.String())
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+const (
+	defaultDescribeDepth = 1
+)
+
+// Describe selects the facts around a subject: the facts mentioning it, as
+// their subject, object or inside their nested facts, and, for every further
+// hop up to Depth, the facts mentioning the subjects mentioned by the facts
+// of the previous hop. If Fact is set, the fact itself is described instead
+// of a subject: it is selected along with the facts around the subjects it
+// mentions.
+type Describe struct {
+	Subject string
+	Fact    *parser.Fact
+	Depth   int
+}
+
+func DescribeFromAST(d *parser.Describe) (*Describe, error) {
+	if d.ID != nil && d.Fact == nil {
+		return nil, fmt.Errorf("fact %s is not resolved", d.ID)
+	}
+	dd := &Describe{
+		Subject: d.Subject,
+		Fact:    d.Fact,
+		Depth:   defaultDescribeDepth,
+	}
+	if d.Depth != nil {
+		dd.Depth = *d.Depth
+	}
+	if dd.Depth < 1 {
+		return nil, fmt.Errorf("describe depth must be positive, got %d", dd.Depth)
+	}
+	return dd, nil
+}
+
+// Facts returns the facts around the subject.
+func (d *Describe) Facts(s Reader) (map[uint32]*parser.Fact, error) {
+	all, err := s.Get(&Query{})
+	if err != nil {
+		return nil, fmt.Errorf("store.Get: %v", err)
+	}
+	mentioning := map[string][]uint32{}
+	for _, id := range sortedIDs(all) {
+		for _, subject := range mentionedSubjects(all[id]) {
+			mentioning[subject] = append(mentioning[subject], id)
+		}
+	}
+
+	described := map[uint32]*parser.Fact{}
+	visited := map[string]bool{d.Subject: true}
+	frontier := []string{d.Subject}
+	if d.Fact != nil {
+		pretty := d.Fact.Pretty()
+		for id, f := range all {
+			if f.Pretty() == pretty {
+				described[id] = f
+			}
+		}
+		visited = map[string]bool{}
+		frontier = mentionedSubjects(d.Fact)
+		for _, s := range frontier {
+			visited[s] = true
+		}
+	}
+	for hop := 0; hop < d.Depth && len(frontier) > 0; hop++ {
+		next := []string{}
+		for _, subject := range frontier {
+			for _, id := range mentioning[subject] {
+				if _, ok := described[id]; ok {
+					continue
+				}
+				described[id] = all[id]
+				for _, m := range mentionedSubjects(all[id]) {
+					if !visited[m] {
+						visited[m] = true
+						next = append(next, m)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return described, nil
+}
+
+func (d *Describe) Pretty() string {
+	if d.Fact != nil {
+		return fmt.Sprintf("describe %s depth %d", d.Fact.Pretty(), d.Depth)
+	}
+	return fmt.Sprintf("describe %s depth %d", d.Subject, d.Depth)
+}
+
+// mentionedSubjects returns the subjects appearing in the fact, including the
+// ones in its nested facts and subject objects, each once.
+func mentionedSubjects(f *parser.Fact) []string {
+	subjects := []string{}
+	seen := map[string]bool{}
+	var walk func(f *parser.Fact)
+	add := func(s *string) {
+		if s != nil && !seen[*s] {
+			seen[*s] = true
+			subjects = append(subjects, *s)
+		}
+	}
+	walk = func(f *parser.Fact) {
+		add(f.Subject)
+		if f.SubjectFact != nil {
+			walk(f.SubjectFact)
+		}
+		if f.Object != nil && f.Object.IsSubject() {
+			add(ptrutils.Ptr(f.Object.InnerValue().(string)))
+		}
+		if f.ObjectFact != nil {
+			walk(f.ObjectFact)
+		}
+	}
+	walk(f)
+	return subjects
+}
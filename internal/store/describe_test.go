@@ -0,0 +1,139 @@
+package store_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		desc     string
+		facts    []string
+		describe string
+		// fact, if set, is referenced by ID in place of the %s of describe.
+		fact    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			desc:     "outgoing, incoming and nested facts",
+			describe: "describe Ozan",
+			want: []string{
+				"((Ozan, knows, CS), approvedBy, METU)",
+				`(Ezgi, notKnows, (Ufuk, knows, (Ozan, knows, CS)))`,
+				"(Ezgi, thinks, (Ozan, is, Person))",
+				"(Ozan, age, 24)",
+				"(Ozan, is, Person)",
+				"(Ozan, knows, CS)",
+				`(Ozan, name, "Ozan Sazak!!!")`,
+				"(Ufuk, knows, (Ozan, knows, CS))",
+			},
+		},
+		{
+			desc:     "incoming facts",
+			describe: "describe METU",
+			want:     []string{"((Ozan, knows, CS), approvedBy, METU)"},
+		},
+		{
+			desc:     "depth",
+			facts:    []string{"(Science, partOf, Knowledge)", "(Knowledge, is, Abstract)"},
+			describe: "describe Science depth 2",
+			want: []string{
+				"((Ozan, knows, CS), approvedBy, METU)",
+				"(CS, subtopicOf, Science)",
+				"(Ezgi, notKnows, (Ufuk, knows, (Ozan, knows, CS)))",
+				"(Knowledge, is, Abstract)",
+				"(Ozan, knows, CS)",
+				"(Science, partOf, Knowledge)",
+				"(Ufuk, knows, (Ozan, knows, CS))",
+				"(Ufuk, knows, CS)",
+			},
+		},
+		{
+			desc:     "unknown subjects",
+			describe: "describe Nobody depth 3",
+			want:     []string{},
+		},
+		{
+			desc:     "fact by ID",
+			describe: "describe %s",
+			fact:     "(Ufuk, knows, CS)",
+			want: []string{
+				"((Ozan, knows, CS), approvedBy, METU)",
+				"(CS, subtopicOf, Science)",
+				"(Ezgi, notKnows, (Ufuk, knows, (Ozan, knows, CS)))",
+				"(Ozan, knows, CS)",
+				"(Ufuk, is, Person)",
+				"(Ufuk, knows, (Ozan, knows, CS))",
+				"(Ufuk, knows, CS)",
+				"(Ufuk, length, 123.456)",
+			},
+		},
+		{
+			desc:     "non-positive depth",
+			describe: "describe Ozan depth 0",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			s := newStore(t, p, tc.facts)
+			line := tc.describe
+			if tc.fact != "" {
+				line = fmt.Sprintf(tc.describe, factID(t, s, tc.fact))
+			}
+			exp, err := p.ParseLine(line)
+			if err != nil {
+				t.Fatalf("failed to parse describe: %v", err)
+			}
+			if err := parser.ResolveFactIDs(exp, s.GetByID); err != nil {
+				t.Fatalf("failed to resolve fact IDs: %v", err)
+			}
+			d, err := store.DescribeFromAST(exp.Describe)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to convert describe: %v", err)
+			}
+			facts, err := d.Facts(s)
+			if err != nil {
+				t.Fatalf("failed to describe: %v", err)
+			}
+			got := []string{}
+			for _, f := range facts {
+				got = append(got, f.Pretty())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected facts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// factID returns the reference to the ID of the fact in the store.
+func factID(t *testing.T, s store.Store, fact string) parser.FactID {
+	t.Helper()
+	all, err := s.Get(&store.Query{})
+	if err != nil {
+		t.Fatalf("failed to get facts: %v", err)
+	}
+	for id, f := range all {
+		if f.Pretty() == fact {
+			return parser.FactID(id)
+		}
+	}
+	t.Fatalf("fact %s not found in store", fact)
+	return 0
+}
@@ -0,0 +1,790 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ozansz/semantix/internal/parser"
+)
+
+// Expr is an expression over the bindings of a solution, used by filter and
+// bind clauses.
+type Expr interface {
+	// Eval returns the value of the expression for the bindings, reading the
+	// store for the functions that query it. The value is nil, without an
+	// error, if the expression is undefined for the bindings because it refers
+	// to an unbound variable.
+	Eval(Reader, Bindings) (*Object, error)
+	Pretty() string
+
+	precedence() int
+	walkVariables(func(string))
+}
+
+// Bind binds the value of the expression to the variable.
+type Bind struct {
+	Expr     Expr
+	Variable string
+}
+
+// Precedences of the expressions, from the loosest to the tightest binding.
+const (
+	precedenceOr = iota
+	precedenceAnd
+	precedenceNot
+	precedenceComparison
+	precedenceSum
+	precedenceProduct
+	precedenceUnary
+	precedencePrimary
+)
+
+// function is a built-in function of expressions. A negative maxArgs allows
+// any number of arguments.
+type function struct {
+	minArgs, maxArgs int
+	eval             func(name string, args []*Object) (*Object, error)
+}
+
+// boundFunction reports whether its variable argument is bound. It is the only
+// function defined for unbound variables, so it is evaluated separately.
+const boundFunction = "bound"
+
+// assertedFunction reports whether its fact argument is asserted, rather than
+// only quoted in other facts. It reads the store, so it is evaluated
+// separately.
+const assertedFunction = "asserted"
+
+var functions = map[string]*function{
+	"str": {1, 1, func(_ string, args []*Object) (*Object, error) {
+		return stringObject(lexicalForm(args[0])), nil
+	}},
+	"lang": {1, 1, func(name string, args []*Object) (*Object, error) {
+		if !isStringKind(args[0].Kind) {
+			return nil, fmt.Errorf("function %s expects a string, got %s", name, args[0].String())
+		}
+		return stringObject(args[0].Lang), nil
+	}},
+	"langmatches": {2, 2, func(name string, args []*Object) (*Object, error) {
+		tag, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := stringArg(name, args[1])
+		if err != nil {
+			return nil, err
+		}
+		// The range * matches every tagged string.
+		if r == "*" {
+			return ObjectFromBool(tag != ""), nil
+		}
+		return ObjectFromBool(parser.LangTag(strings.ToLower(tag)).Matches(parser.LangTag(strings.ToLower(r)))), nil
+	}},
+	"datatype": {1, 1, func(name string, args []*Object) (*Object, error) {
+		if !isStringKind(args[0].Kind) {
+			return nil, fmt.Errorf("function %s expects a string, got %s", name, args[0].String())
+		}
+		if args[0].Datatype == "" {
+			return nil, nil
+		}
+		return ObjectFromSubject(args[0].Datatype), nil
+	}},
+	"strlen": {1, 1, func(name string, args []*Object) (*Object, error) {
+		s, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return intObject(int64(utf8.RuneCountInString(s))), nil
+	}},
+	"upper": {1, 1, func(name string, args []*Object) (*Object, error) {
+		s, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return stringObject(strings.ToUpper(s)), nil
+	}},
+	"lower": {1, 1, func(name string, args []*Object) (*Object, error) {
+		s, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return stringObject(strings.ToLower(s)), nil
+	}},
+	"concat": {1, -1, func(name string, args []*Object) (*Object, error) {
+		var sb strings.Builder
+		for _, a := range args {
+			s, err := stringArg(name, a)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(s)
+		}
+		return stringObject(sb.String()), nil
+	}},
+	"substr": {2, 3, func(name string, args []*Object) (*Object, error) {
+		s, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(s)
+		// Positions are 1-based, as in SPARQL.
+		start, err := numberArg(name, args[1])
+		if err != nil {
+			return nil, err
+		}
+		end := math.Inf(1)
+		if len(args) == 3 {
+			length, err := numberArg(name, args[2])
+			if err != nil {
+				return nil, err
+			}
+			end = start + length
+		}
+		if math.IsNaN(start) || math.IsInf(start, 0) || math.IsNaN(end) {
+			return nil, fmt.Errorf("function %s expects finite positions", name)
+		}
+		from, to := runePosition(start, len(runes)), runePosition(end, len(runes))
+		if from >= to {
+			return stringObject(""), nil
+		}
+		return stringObject(string(runes[from:to])), nil
+	}},
+	"contains":  {2, 2, stringPredicate(strings.Contains)},
+	"strstarts": {2, 2, stringPredicate(strings.HasPrefix)},
+	"strends":   {2, 2, stringPredicate(strings.HasSuffix)},
+	"abs":       {1, 1, numberFunction(math.Abs, absInt)},
+	"round":     {1, 1, numberFunction(math.Round, identityInt)},
+	"floor":     {1, 1, numberFunction(math.Floor, identityInt)},
+	"ceil":      {1, 1, numberFunction(math.Ceil, identityInt)},
+}
+
+func ExprFromAST(e *parser.Expr) (Expr, error) {
+	operands := []Expr{}
+	for _, c := range e.Or {
+		o, err := conjunctionFromAST(c)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, o)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &logicalExpr{or: true, operands: operands}, nil
+}
+
+func BindFromAST(b *parser.Bind) (*Bind, error) {
+	e, err := ExprFromAST(b.Expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Bind{Expr: e, Variable: b.Variable}, nil
+}
+
+func conjunctionFromAST(c *parser.Conjunction) (Expr, error) {
+	operands := []Expr{}
+	for _, n := range c.And {
+		o, err := comparisonFromAST(n.Comparison)
+		if err != nil {
+			return nil, err
+		}
+		if n.Not {
+			o = &notExpr{operand: o}
+		}
+		operands = append(operands, o)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &logicalExpr{operands: operands}, nil
+}
+
+func comparisonFromAST(c *parser.Comparison) (Expr, error) {
+	left, err := sumFromAST(c.Left)
+	if err != nil {
+		return nil, err
+	}
+	if c.Operator == nil {
+		return left, nil
+	}
+	right, err := sumFromAST(c.Right)
+	if err != nil {
+		return nil, err
+	}
+	ce := &compareExpr{
+		operator: ComparisonOperator(*c.Operator),
+		left:     left,
+		right:    right,
+	}
+	// Comparisons against constants, like regular expressions, are only
+	// validated and compiled once.
+	if l, ok := right.(*literalExpr); ok {
+		if ce.constant, err = NewComparison(ce.operator, l.value); err != nil {
+			return nil, err
+		}
+	}
+	return ce, nil
+}
+
+func sumFromAST(s *parser.Sum) (Expr, error) {
+	e, err := productFromAST(s.Left)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range s.Rest {
+		right, err := productFromAST(op.Operand)
+		if err != nil {
+			return nil, err
+		}
+		e = &arithmeticExpr{operator: op.Operator, left: e, right: right}
+	}
+	return e, nil
+}
+
+func productFromAST(p *parser.Product) (Expr, error) {
+	e, err := unaryFromAST(p.Left)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range p.Rest {
+		right, err := unaryFromAST(op.Operand)
+		if err != nil {
+			return nil, err
+		}
+		e = &arithmeticExpr{operator: op.Operator, left: e, right: right}
+	}
+	return e, nil
+}
+
+func unaryFromAST(u *parser.Unary) (Expr, error) {
+	e, err := primaryFromAST(u.Operand)
+	if err != nil {
+		return nil, err
+	}
+	if u.Negative {
+		return &negateExpr{operand: e}, nil
+	}
+	return e, nil
+}
+
+func primaryFromAST(p *parser.Primary) (Expr, error) {
+	switch {
+	case p.Number != nil:
+		return &literalExpr{value: floatObject(*p.Number)}, nil
+	case p.Integer != nil:
+		return &literalExpr{value: intObject(*p.Integer)}, nil
+	case p.Date != nil:
+		return &literalExpr{value: timeObject(p.Date.Time, ObjectKindDate)}, nil
+	case p.Timestamp != nil:
+		return &literalExpr{value: timeObject(*p.Timestamp, ObjectKindTimestamp)}, nil
+	case p.String != nil:
+		return &literalExpr{value: ObjectFromAST(*p.String)}, nil
+	case p.Bool != nil:
+		return &literalExpr{value: ObjectFromBool(*p.Bool == "true")}, nil
+	case p.Variable != nil:
+		return &variableExpr{name: *p.Variable}, nil
+	case p.Subject != nil:
+		return &literalExpr{value: ObjectFromSubject(*p.Subject)}, nil
+	case p.Sub != nil:
+		return ExprFromAST(p.Sub)
+	}
+	return callFromAST(p.Call)
+}
+
+func callFromAST(c *parser.Call) (Expr, error) {
+	args := []Expr{}
+	for _, a := range c.Args {
+		e, err := ExprFromAST(a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+	}
+	if c.Function == assertedFunction {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("function %s expects 1 argument, got %d", c.Function, len(args))
+		}
+		return &callExpr{function: c.Function, args: args}, nil
+	}
+	if c.Function == boundFunction {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("function %s expects 1 argument, got %d", c.Function, len(args))
+		}
+		if _, ok := args[0].(*variableExpr); !ok {
+			return nil, fmt.Errorf("function %s expects a variable, got %s", c.Function, args[0].Pretty())
+		}
+		return &callExpr{function: c.Function, args: args}, nil
+	}
+	f, ok := functions[c.Function]
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", c.Function)
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, fmt.Errorf("function %s does not accept %d argument(s)", c.Function, len(args))
+	}
+	return &callExpr{function: c.Function, args: args, f: f}, nil
+}
+
+// logicalExpr is a conjunction, or a disjunction if or. Operands are
+// evaluated in order: a false operand of a conjunction, or a true operand of
+// a disjunction, decides the value even if other operands are undefined.
+type logicalExpr struct {
+	or       bool
+	operands []Expr
+}
+
+func (e *logicalExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	undefined := false
+	for _, o := range e.operands {
+		v, err := evalBool(s, o, b)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			undefined = true
+		} else if *v == e.or {
+			return ObjectFromBool(e.or), nil
+		}
+	}
+	if undefined {
+		return nil, nil
+	}
+	return ObjectFromBool(!e.or), nil
+}
+
+func (e *logicalExpr) Pretty() string {
+	operator, operands := " and ", []string{}
+	if e.or {
+		operator = " or "
+	}
+	for _, o := range e.operands {
+		operands = append(operands, prettyOperand(o, e.precedence()+1))
+	}
+	return strings.Join(operands, operator)
+}
+
+func (e *logicalExpr) precedence() int {
+	if e.or {
+		return precedenceOr
+	}
+	return precedenceAnd
+}
+
+func (e *logicalExpr) walkVariables(fn func(string)) {
+	for _, o := range e.operands {
+		o.walkVariables(fn)
+	}
+}
+
+type notExpr struct {
+	operand Expr
+}
+
+func (e *notExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	v, err := evalBool(s, e.operand, b)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	return ObjectFromBool(!*v), nil
+}
+
+func (e *notExpr) Pretty() string {
+	return "not " + prettyOperand(e.operand, precedenceComparison)
+}
+
+func (e *notExpr) precedence() int               { return precedenceNot }
+func (e *notExpr) walkVariables(fn func(string)) { e.operand.walkVariables(fn) }
+
+// compareExpr compares its operands as object filters do, with = testing for
+// equality.
+type compareExpr struct {
+	operator    ComparisonOperator
+	left, right Expr
+	constant    *Comparison
+}
+
+func (e *compareExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	l, err := e.left.Eval(s, b)
+	if l == nil || err != nil {
+		return nil, err
+	}
+	c := e.constant
+	if c == nil {
+		r, err := e.right.Eval(s, b)
+		if r == nil || err != nil {
+			return nil, err
+		}
+		if c, err = NewComparison(e.operator, r); err != nil {
+			return nil, err
+		}
+	}
+	return ObjectFromBool(c.Holds(l)), nil
+}
+
+func (e *compareExpr) Pretty() string {
+	return fmt.Sprintf("%s %s %s", prettyOperand(e.left, precedenceSum), e.operator, prettyOperand(e.right, precedenceSum))
+}
+
+func (e *compareExpr) precedence() int { return precedenceComparison }
+
+func (e *compareExpr) walkVariables(fn func(string)) {
+	e.left.walkVariables(fn)
+	e.right.walkVariables(fn)
+}
+
+// arithmeticExpr applies one of the +, -, *, / and % operators to numbers.
+// Integers stay integers, except for divisions, and are promoted to floats
+// when combined with them.
+type arithmeticExpr struct {
+	operator    string
+	left, right Expr
+}
+
+func (e *arithmeticExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	l, err := e.left.Eval(s, b)
+	if l == nil || err != nil {
+		return nil, err
+	}
+	r, err := e.right.Eval(s, b)
+	if r == nil || err != nil {
+		return nil, err
+	}
+	if !isNumberKind(l.Kind) || !isNumberKind(r.Kind) {
+		return nil, fmt.Errorf("operator %s expects numbers, got %s and %s", e.operator, l.String(), r.String())
+	}
+	if (e.operator == "/" || e.operator == "%") && floatValue(r) == 0 {
+		return nil, fmt.Errorf("division by zero: %s", e.Pretty())
+	}
+	if l.Kind == ObjectKindInt && r.Kind == ObjectKindInt && e.operator != "/" {
+		v, err := intArithmetic(e.operator, *l.IntValue, *r.IntValue)
+		if err != nil {
+			return nil, err
+		}
+		return intObject(v), nil
+	}
+	x, y := floatValue(l), floatValue(r)
+	switch e.operator {
+	case "+":
+		return floatObject(x + y), nil
+	case "-":
+		return floatObject(x - y), nil
+	case "*":
+		return floatObject(x * y), nil
+	case "/":
+		return floatObject(x / y), nil
+	}
+	return floatObject(math.Mod(x, y)), nil
+}
+
+func (e *arithmeticExpr) Pretty() string {
+	// Operators are left associative, so only the right operand needs
+	// parentheses when it binds as loosely.
+	return fmt.Sprintf("%s %s %s", prettyOperand(e.left, e.precedence()), e.operator, prettyOperand(e.right, e.precedence()+1))
+}
+
+func (e *arithmeticExpr) precedence() int {
+	if e.operator == "+" || e.operator == "-" {
+		return precedenceSum
+	}
+	return precedenceProduct
+}
+
+func (e *arithmeticExpr) walkVariables(fn func(string)) {
+	e.left.walkVariables(fn)
+	e.right.walkVariables(fn)
+}
+
+type negateExpr struct {
+	operand Expr
+}
+
+func (e *negateExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	v, err := e.operand.Eval(s, b)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	switch v.Kind {
+	case ObjectKindInt:
+		n, err := negateInt(*v.IntValue)
+		if err != nil {
+			return nil, err
+		}
+		return intObject(n), nil
+	case ObjectKindFloat:
+		return floatObject(-*v.FloatValue), nil
+	}
+	return nil, fmt.Errorf("operator - expects a number, got %s", v.String())
+}
+
+func (e *negateExpr) Pretty() string {
+	return "-" + prettyOperand(e.operand, precedencePrimary)
+}
+
+func (e *negateExpr) precedence() int               { return precedenceUnary }
+func (e *negateExpr) walkVariables(fn func(string)) { e.operand.walkVariables(fn) }
+
+type literalExpr struct {
+	value *Object
+}
+
+func (e *literalExpr) Eval(Reader, Bindings) (*Object, error) { return e.value, nil }
+func (e *literalExpr) Pretty() string                         { return e.value.String() }
+func (e *literalExpr) precedence() int                        { return precedencePrimary }
+func (e *literalExpr) walkVariables(func(string))             {}
+
+type variableExpr struct {
+	name string
+}
+
+func (e *variableExpr) Eval(_ Reader, b Bindings) (*Object, error) { return b[e.name], nil }
+func (e *variableExpr) Pretty() string                             { return e.name }
+func (e *variableExpr) precedence() int                            { return precedencePrimary }
+func (e *variableExpr) walkVariables(fn func(string))              { fn(e.name) }
+
+type callExpr struct {
+	function string
+	args     []Expr
+	f        *function
+}
+
+func (e *callExpr) Eval(s Reader, b Bindings) (*Object, error) {
+	if e.function == boundFunction {
+		_, ok := b[e.args[0].(*variableExpr).name]
+		return ObjectFromBool(ok), nil
+	}
+	if e.function == assertedFunction {
+		v, err := e.args[0].Eval(s, b)
+		if v == nil || err != nil {
+			return nil, err
+		}
+		if v.Kind != ObjectKindFact {
+			return nil, fmt.Errorf("function %s expects a fact, got %s", e.function, v.String())
+		}
+		asserted, err := isAsserted(s, v.FactValue)
+		if err != nil {
+			return nil, err
+		}
+		return ObjectFromBool(asserted), nil
+	}
+	args := make([]*Object, 0, len(e.args))
+	for _, a := range e.args {
+		v, err := a.Eval(s, b)
+		if v == nil || err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return e.f.eval(e.function, args)
+}
+
+func (e *callExpr) Pretty() string {
+	args := []string{}
+	for _, a := range e.args {
+		args = append(args, a.Pretty())
+	}
+	return fmt.Sprintf("%s(%s)", e.function, strings.Join(args, ", "))
+}
+
+func (e *callExpr) precedence() int { return precedencePrimary }
+
+func (e *callExpr) walkVariables(fn func(string)) {
+	for _, a := range e.args {
+		a.walkVariables(fn)
+	}
+}
+
+func (b *Bind) Pretty() string {
+	return fmt.Sprintf("%s as %s", b.Expr.Pretty(), b.Variable)
+}
+
+// joinFilter keeps the rows for which the expression is true.
+func joinFilter(s Reader, rows []*Row, e Expr) ([]*Row, error) {
+	kept := []*Row{}
+	for _, r := range rows {
+		v, err := evalBool(s, e, r.Bindings)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && *v {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// joinBind binds the value of the expression to the variable in every row.
+// The variable is left unbound in rows for which the expression is undefined,
+// and rows that already bind the variable to another value are dropped.
+func joinBind(s Reader, rows []*Row, bind *Bind) ([]*Row, error) {
+	joined := []*Row{}
+	for _, r := range rows {
+		v, err := bind.Expr.Eval(s, r.Bindings)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			joined = append(joined, r)
+			continue
+		}
+		b := r.Bindings.Copy()
+		if !b.bind(bind.Variable, v) {
+			continue
+		}
+		joined = append(joined, &Row{Bindings: b, FactIDs: r.FactIDs, Facts: r.Facts})
+	}
+	return joined, nil
+}
+
+// evalBool evaluates an expression expected to be a boolean.
+func evalBool(s Reader, e Expr, b Bindings) (*bool, error) {
+	v, err := e.Eval(s, b)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	if v.Kind != ObjectKindBool {
+		return nil, fmt.Errorf("expected a boolean, %s is %s", e.Pretty(), v.String())
+	}
+	return v.BoolValue, nil
+}
+
+func prettyOperand(e Expr, precedence int) string {
+	if e.precedence() < precedence {
+		return "(" + e.Pretty() + ")"
+	}
+	return e.Pretty()
+}
+
+// lexicalForm returns the value as a plain string, without the quotes of
+// strings.
+func lexicalForm(o *Object) string {
+	switch o.Kind {
+	case ObjectKindSubject, ObjectKindString:
+		return *o.StringValue
+	case ObjectKindFloat:
+		return strconv.FormatFloat(*o.FloatValue, 'f', -1, 64)
+	case ObjectKindInt:
+		return strconv.FormatInt(*o.IntValue, 10)
+	}
+	return o.String()
+}
+
+func stringArg(function string, o *Object) (string, error) {
+	if !isStringKind(o.Kind) {
+		return "", fmt.Errorf("function %s expects a string, got %s", function, o.String())
+	}
+	return *o.StringValue, nil
+}
+
+func numberArg(function string, o *Object) (float64, error) {
+	if !isNumberKind(o.Kind) {
+		return 0, fmt.Errorf("function %s expects a number, got %s", function, o.String())
+	}
+	return floatValue(o), nil
+}
+
+// runePosition returns the index of the 1-based position in a string of n
+// runes, clamped to the bounds of the string.
+func runePosition(pos float64, n int) int {
+	return int(math.Min(math.Max(pos-1, 0), float64(n)))
+}
+
+// floatValue returns the value of an integer or a float as a float.
+func floatValue(o *Object) float64 {
+	if o.Kind == ObjectKindInt {
+		return float64(*o.IntValue)
+	}
+	return *o.FloatValue
+}
+
+// addNumbers returns the sum of the numbers, an integer if both are.
+func addNumbers(a, b *Object) (*Object, error) {
+	if a.Kind == ObjectKindInt && b.Kind == ObjectKindInt {
+		v, err := intArithmetic("+", *a.IntValue, *b.IntValue)
+		if err != nil {
+			return nil, err
+		}
+		return intObject(v), nil
+	}
+	return floatObject(floatValue(a) + floatValue(b)), nil
+}
+
+// intArithmetic applies the operator to the integers. Results that do not fit
+// in an int64 are an error rather than wrapping around.
+func intArithmetic(operator string, x, y int64) (int64, error) {
+	var v int64
+	overflow := false
+	switch operator {
+	case "+":
+		v = x + y
+		overflow = (x >= 0) == (y >= 0) && (v >= 0) != (x >= 0)
+	case "-":
+		v = x - y
+		overflow = (x >= 0) != (y >= 0) && (v >= 0) != (x >= 0)
+	case "*":
+		v = x * y
+		overflow = x != 0 && (v/x != y || (x == -1 && y == math.MinInt64))
+	case "%":
+		v = x % y
+	}
+	if overflow {
+		return 0, fmt.Errorf("integer overflow: %d %s %d", x, operator, y)
+	}
+	return v, nil
+}
+
+func stringPredicate(fn func(s, sub string) bool) func(string, []*Object) (*Object, error) {
+	return func(name string, args []*Object) (*Object, error) {
+		s, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		sub, err := stringArg(name, args[1])
+		if err != nil {
+			return nil, err
+		}
+		return ObjectFromBool(fn(s, sub)), nil
+	}
+}
+
+// numberFunction returns a function applying fn to float arguments and intFn
+// to integer ones.
+func numberFunction(fn func(float64) float64, intFn func(int64) (int64, error)) func(string, []*Object) (*Object, error) {
+	return func(name string, args []*Object) (*Object, error) {
+		x, err := numberArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if args[0].Kind == ObjectKindInt {
+			v, err := intFn(*args[0].IntValue)
+			if err != nil {
+				return nil, fmt.Errorf("function %s: %v", name, err)
+			}
+			return intObject(v), nil
+		}
+		return floatObject(fn(x)), nil
+	}
+}
+
+func absInt(i int64) (int64, error) {
+	if i < 0 {
+		return negateInt(i)
+	}
+	return i, nil
+}
+
+// negateInt returns -i, which does not fit in an int64 for the minimum value.
+func negateInt(i int64) (int64, error) {
+	if i == math.MinInt64 {
+		return 0, fmt.Errorf("integer overflow: -(%d)", i)
+	}
+	return -i, nil
+}
+
+func identityInt(i int64) (int64, error) { return i, nil }
+
+func stringObject(s string) *Object {
+	return &Object{StringValue: &s, Kind: ObjectKindString}
+}
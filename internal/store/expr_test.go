@@ -0,0 +1,125 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSubstr(t *testing.T) {
+	tests := []struct {
+		desc    string
+		args    []*Object
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "start",
+			args: []*Object{stringObject("abcde"), intObject(2)},
+			want: "bcde",
+		},
+		{
+			desc: "start and length",
+			args: []*Object{stringObject("abcde"), intObject(2), intObject(3)},
+			want: "bcd",
+		},
+		{
+			desc: "multibyte runes",
+			args: []*Object{stringObject("çğüşö"), intObject(2), intObject(2)},
+			want: "ğü",
+		},
+		{
+			desc: "negative start",
+			args: []*Object{stringObject("abcde"), intObject(-1), intObject(3)},
+			want: "a",
+		},
+		{
+			desc: "start past the end",
+			args: []*Object{stringObject("abcde"), intObject(10)},
+			want: "",
+		},
+		{
+			desc: "length past the end",
+			args: []*Object{stringObject("abcde"), intObject(4), intObject(10)},
+			want: "de",
+		},
+		{
+			desc: "negative length",
+			args: []*Object{stringObject("abcde"), intObject(2), intObject(-1)},
+			want: "",
+		},
+		{
+			desc: "huge start",
+			args: []*Object{stringObject("abcde"), floatObject(1e300)},
+			want: "",
+		},
+		{
+			desc: "infinite length",
+			args: []*Object{stringObject("abcde"), intObject(2), floatObject(math.Inf(1))},
+			want: "bcde",
+		},
+		{
+			desc:    "NaN start",
+			args:    []*Object{stringObject("abcde"), floatObject(math.NaN())},
+			wantErr: true,
+		},
+		{
+			desc:    "infinite start",
+			args:    []*Object{stringObject("abcde"), floatObject(math.Inf(-1))},
+			wantErr: true,
+		},
+		{
+			desc:    "NaN length",
+			args:    []*Object{stringObject("abcde"), intObject(1), floatObject(math.NaN())},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := functions["substr"].eval("substr", tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("substr() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *got.StringValue != tc.want {
+				t.Errorf("substr() = %q, want %q", *got.StringValue, tc.want)
+			}
+		})
+	}
+}
+
+func TestIntArithmetic(t *testing.T) {
+	tests := []struct {
+		desc     string
+		operator string
+		x, y     int64
+		want     int64
+		wantErr  bool
+	}{
+		{desc: "sum", operator: "+", x: 40, y: 2, want: 42},
+		{desc: "sum overflow", operator: "+", x: math.MaxInt64, y: 1, wantErr: true},
+		{desc: "sum underflow", operator: "+", x: math.MinInt64, y: -1, wantErr: true},
+		{desc: "difference", operator: "-", x: -40, y: 2, want: -42},
+		{desc: "difference overflow", operator: "-", x: math.MaxInt64, y: -1, wantErr: true},
+		{desc: "zero minus minimum", operator: "-", x: 0, y: math.MinInt64, wantErr: true},
+		{desc: "product", operator: "*", x: -6, y: 7, want: -42},
+		{desc: "product overflow", operator: "*", x: math.MaxInt64 / 2, y: 3, wantErr: true},
+		{desc: "minimum times minus one", operator: "*", x: math.MinInt64, y: -1, wantErr: true},
+		{desc: "minus one times minimum", operator: "*", x: -1, y: math.MinInt64, wantErr: true},
+		{desc: "remainder", operator: "%", x: 43, y: 7, want: 1},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := intArithmetic(tc.operator, tc.x, tc.y)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("intArithmetic() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("intArithmetic() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
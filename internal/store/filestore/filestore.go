@@ -1,16 +1,17 @@
 package filestore
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ozansz/semantix/internal/parser"
 	"github.com/ozansz/semantix/internal/store"
-
-	"github.com/zhenjl/cityhash"
 )
 
 const (
@@ -19,13 +20,22 @@ const (
 )
 
 type FileStore struct {
-	fp            *os.File
-	debug         bool
-	path          string
-	persistent    bool
-	store         sync.Map
+	fp         *os.File
+	debug      bool
+	path       string
+	persistent bool
+	store      sync.Map
+	// idBuffer holds the IDs of the facts changed since the last flush, and
+	// whether the records of the fact are to be tombstoned before writing the
+	// current ones, which is the case once the fact lost a graph.
 	idBuffer      sync.Map
+	graphsMu      sync.Mutex
+	graphs        map[uint32]map[string]bool
 	storeSyncDone chan struct{}
+	storeSyncExit chan struct{}
+	rulesMu       sync.Mutex
+	rules         []*store.Rule
+	derived       map[uint32]*parser.Fact
 }
 
 type FileStoreOption func(*FileStore)
@@ -47,7 +57,9 @@ func New(opts ...FileStoreOption) (*FileStore, error) {
 	fs := &FileStore{
 		store:         sync.Map{},
 		idBuffer:      sync.Map{},
+		graphs:        map[uint32]map[string]bool{},
 		storeSyncDone: make(chan struct{}),
+		storeSyncExit: make(chan struct{}),
 		debug:         false,
 	}
 	for _, o := range opts {
@@ -56,17 +68,67 @@ func New(opts ...FileStoreOption) (*FileStore, error) {
 
 	var err error
 	if fs.persistent {
-		fs.fp, err = os.Create(fs.path)
+		fs.fp, err = os.OpenFile(fs.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, defaultStoreFileMode)
 		if err != nil {
 			return nil, fmt.Errorf("os.OpenFile: %v", err)
 		}
+		if err := fs.load(); err != nil {
+			fs.fp.Close()
+			return nil, fmt.Errorf("fs.load: %v", err)
+		}
 		go fs.syncWorker(fs.storeSyncDone)
 	}
 
 	return fs, nil
 }
 
+// load replays the records of the store file, in the order they were written.
+// Records of facts in named graphs are prefixed with the graph, as in
+// [HR] (...).
+func (fs *FileStore) load() error {
+	p := parser.New()
+	// rekeyed maps the IDs records were written with to the IDs of their facts,
+	// for the facts whose hash changed since, e.g. as numbers were written with
+	// six decimals.
+	rekeyed := map[uint32]uint32{}
+	scanner := bufio.NewScanner(fs.fp)
+	for line := 1; scanner.Scan(); line++ {
+		record := strings.TrimSpace(scanner.Text())
+		if record == "" {
+			continue
+		}
+		if strings.HasPrefix(record, "-") {
+			id, err := tombstoneDecode(record)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			if h, ok := rekeyed[id]; ok {
+				id = h
+			}
+			fs.store.Delete(id)
+			delete(fs.graphs, id)
+			continue
+		}
+		graph, record, err := graphDecode(record)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		id, t, err := tripleDecode(p, record)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if h := store.FactHash(t); h != id {
+			rekeyed[id] = h
+			id = h
+		}
+		fs.store.Store(id, t)
+		fs.addGraph(id, graph)
+	}
+	return scanner.Err()
+}
+
 func (fs *FileStore) syncWorker(done <-chan struct{}) {
+	defer close(fs.storeSyncExit)
 	t := time.NewTicker(defaultFlushInterval)
 	for {
 		select {
@@ -88,17 +150,23 @@ func (fs *FileStore) flush() {
 
 		fs.idBuffer.Range(func(key, value any) bool {
 			id := key.(uint32)
+			records := [][]byte{}
 			t, ok := fs.store.Load(id)
-			if !ok {
-				errs = append(errs, fmt.Errorf("triple with id %d not found in store", id))
-				return true
+			if !ok || value.(bool) {
+				records = append(records, tombstoneEncode(id))
 			}
-			enc := tripleEncode(id, t.(*parser.Fact))
-			if _, err := fs.fp.Write(enc); err != nil {
-				errs = append(errs, err)
+			if ok {
+				for _, g := range fs.graphsOf(id) {
+					records = append(records, graphEncode(g, tripleEncode(id, t.(*parser.Fact))))
+				}
 			}
-			if _, err := fs.fp.Write([]byte("\n")); err != nil {
-				errs = append(errs, err)
+			for _, enc := range records {
+				if _, err := fs.fp.Write(enc); err != nil {
+					errs = append(errs, err)
+				}
+				if _, err := fs.fp.Write([]byte("\n")); err != nil {
+					errs = append(errs, err)
+				}
 			}
 			return true
 		})
@@ -123,19 +191,153 @@ func (fs *FileStore) flush() {
 func (fs *FileStore) Close() error {
 	if fs.persistent {
 		close(fs.storeSyncDone)
+		<-fs.storeSyncExit
 		return fs.fp.Close()
 	}
 	return nil
 }
 
 func (fs *FileStore) Add(t *parser.Fact) error {
-	h := tripleHash(t)
+	if err := store.CheckFacts(t); err != nil {
+		return err
+	}
+	fs.add(t, store.DefaultGraph)
+	fs.invalidateDerived()
+	return nil
+}
+
+// AddAll adds all of the facts. The facts are checked before adding any of
+// them, then added in order, invalidating the derived facts once.
+func (fs *FileStore) AddAll(ts []*parser.Fact) error {
+	if err := store.CheckFacts(ts...); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		fs.add(t, store.DefaultGraph)
+	}
+	fs.invalidateDerived()
+	return nil
+}
+
+// add stores the fact in the graphs.
+func (fs *FileStore) add(t *parser.Fact, graphs ...string) {
+	h := store.FactHash(t)
 	fs.store.Store(h, t.Copy())
+	for _, g := range graphs {
+		fs.addGraph(h, g)
+	}
+	fs.idBuffer.LoadOrStore(h, false)
+}
+
+// Delete removes the fact from the store, from all of its graphs. The removal
+// is persisted as a tombstone record, so the fact is not restored when the
+// store is reloaded.
+func (fs *FileStore) Delete(t *parser.Fact) error {
+	h := store.FactHash(t)
+	if _, ok := fs.remove(h); !ok {
+		return fmt.Errorf("triple with id %d not found in store", h)
+	}
+	fs.invalidateDerived()
+	return nil
+}
+
+// remove removes the fact with the ID from the store, returning its graphs.
+func (fs *FileStore) remove(h uint32) ([]string, bool) {
+	if _, ok := fs.store.LoadAndDelete(h); !ok {
+		return nil, false
+	}
+	graphs := fs.graphsOf(h)
+	fs.graphsMu.Lock()
+	delete(fs.graphs, h)
+	fs.graphsMu.Unlock()
 	fs.idBuffer.Store(h, true)
+	return graphs, true
+}
+
+// Replace replaces the old fact with the new one. Facts are stored under the
+// hash of their content, so the new fact is stored under its own ID and the
+// old ID is tombstoned. Facts nesting the old fact, at any depth, are
+// replaced alike with copies nesting the new fact. Replacing facts keep the
+// graphs of the facts they replace.
+func (fs *FileStore) Replace(old, new *parser.Fact) error {
+	if err := store.CheckFacts(new); err != nil {
+		return err
+	}
+	h := store.FactHash(old)
+	graphs, ok := fs.remove(h)
+	if !ok {
+		return fmt.Errorf("triple with id %d not found in store", h)
+	}
+
+	// Nesting facts are collected before storing the new fact, which may
+	// itself nest the old one.
+	nesting := map[uint32]*parser.Fact{}
+	fs.store.Range(func(key, value any) bool {
+		if f, ok := store.ReplaceNested(value.(*parser.Fact), old, new); ok {
+			nesting[key.(uint32)] = f
+		}
+		return true
+	})
+	delete(nesting, store.FactHash(new))
+	fs.add(new, graphs...)
+	nestingGraphs := map[uint32][]string{}
+	for id := range nesting {
+		nestingGraphs[id], _ = fs.remove(id)
+	}
+	for id, f := range nesting {
+		fs.add(f, nestingGraphs[id]...)
+	}
+	fs.invalidateDerived()
 	return nil
 }
 
+// Get returns the facts matching the query, including the ones derived by
+// the registered rules.
 func (fs *FileStore) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
+	derived, err := fs.derivedFacts()
+	if err != nil {
+		return nil, err
+	}
+	trs := fs.getAsserted(q)
+	for id, t := range derived {
+		if q.Matches(t) {
+			trs[id] = t.Copy()
+		}
+	}
+	return trs, nil
+}
+
+// GetByID returns the fact with the given ID, looking it up among the derived
+// facts if it is not asserted.
+func (fs *FileStore) GetByID(id uint32) (*parser.Fact, error) {
+	if t, ok := fs.store.Load(id); ok {
+		return t.(*parser.Fact).Copy(), nil
+	}
+	derived, err := fs.derivedFacts()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := derived[id]; ok {
+		return t.Copy(), nil
+	}
+	return nil, fmt.Errorf("triple with id %d not found in store", id)
+}
+
+// Asserted returns a reader of the asserted facts of the store.
+func (fs *FileStore) Asserted() store.Reader {
+	return assertedView{fs: fs}
+}
+
+// assertedView reads the asserted facts of the store.
+type assertedView struct {
+	fs *FileStore
+}
+
+func (v assertedView) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
+	return v.fs.getAsserted(q), nil
+}
+
+func (fs *FileStore) getAsserted(q *store.Query) map[uint32]*parser.Fact {
 	trs := map[uint32]*parser.Fact{}
 
 	fs.store.Range(func(key, value any) bool {
@@ -146,7 +348,7 @@ func (fs *FileStore) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
 		return true
 	})
 
-	return trs, nil
+	return trs
 }
 
 func (fs *FileStore) Sync() error {
@@ -154,25 +356,6 @@ func (fs *FileStore) Sync() error {
 	return nil
 }
 
-func tripleHash(t *parser.Fact) uint32 {
-	var s string
-	if t.Subject != nil {
-		if t.Object != nil {
-			s = fmt.Sprintf("s:%q|p:%q|o:%d:%q", *t.Subject, t.Predicate, t.Object.Kind(), t.Object.String())
-		} else {
-			s = fmt.Sprintf("s:%q|p:%q|o:(%s)", *t.Subject, t.Predicate, t.ObjectFact.Pretty())
-		}
-	} else {
-		if t.Object != nil {
-			s = fmt.Sprintf("s:(%s)|p:%q|o:%d:%q", t.SubjectFact.Pretty(), t.Predicate, t.Object.Kind(), t.Object.String())
-		} else {
-			s = fmt.Sprintf("s:(%s)|p:%q|o:(%s)", t.SubjectFact.Pretty(), t.Predicate, t.ObjectFact.Pretty())
-		}
-	}
-	b := []byte(s)
-	return cityhash.CityHash32(b, uint32(len(b)))
-}
-
 func tripleEncode(id uint32, t *parser.Fact) []byte {
 	var s string
 	if t.Subject != nil {
@@ -190,3 +373,96 @@ func tripleEncode(id uint32, t *parser.Fact) []byte {
 	}
 	return []byte(s)
 }
+
+// tripleDecode parses a record written by tripleEncode.
+func tripleDecode(p *parser.Parser, record string) (uint32, *parser.Fact, error) {
+	if !strings.HasPrefix(record, "(") || !strings.HasSuffix(record, ")") {
+		return 0, nil, fmt.Errorf("malformed record: %s", record)
+	}
+	fields := splitRecord(record[1 : len(record)-1])
+	var subject, predicate, object string
+	kind := -1
+	switch len(fields) {
+	case 4:
+		subject, predicate, object = fields[1], fields[2], unwrapRecordField(fields[3])
+	case 5:
+		subject, predicate, object = fields[1], fields[2], fields[4]
+		k, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed record object kind: %v", err)
+		}
+		kind = k
+	default:
+		return 0, nil, fmt.Errorf("malformed record: %s", record)
+	}
+	id, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed record id: %v", err)
+	}
+	exp, err := p.ParseLine(fmt.Sprintf("(%s, %s, %s)", unwrapRecordField(subject), predicate, object))
+	if err != nil {
+		return 0, nil, fmt.Errorf("p.ParseLine: %v", err)
+	}
+	if exp.Fact == nil {
+		return 0, nil, fmt.Errorf("record is not a fact: %s", record)
+	}
+	// Subjects written before literals of their spelling existed, such as
+	// true, stay subjects.
+	if parser.ObjectKind(kind) == parser.ObjectKindSubject && exp.Fact.Object != nil && !exp.Fact.Object.IsSubject() {
+		exp.Fact.Object = parser.SubjectObject{Value: object}
+	}
+	return uint32(id), exp.Fact, nil
+}
+
+func tombstoneEncode(id uint32) []byte {
+	return []byte(fmt.Sprintf("-(%d)", id))
+}
+
+func tombstoneDecode(record string) (uint32, error) {
+	var id uint32
+	if _, err := fmt.Sscanf(record, "-(%d)", &id); err != nil {
+		return 0, fmt.Errorf("malformed tombstone: %s", record)
+	}
+	return id, nil
+}
+
+// splitRecord splits the record on the commas that are neither nested in
+// parentheses nor inside strings or IRIs. Strings escape their quotes and IRIs
+// can not contain '>', so both are read up to their end whatever they hold.
+func splitRecord(s string) []string {
+	fields := []string{}
+	depth, start := 0, 0
+	quoted, escaped, iri := false, false, false
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case iri:
+			iri = c != '>'
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '<':
+			iri = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			fields = append(fields, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(fields, strings.TrimSpace(s[start:]))
+}
+
+// unwrapRecordField removes the extra parentheses tripleEncode puts around
+// nested facts.
+func unwrapRecordField(s string) string {
+	if strings.HasPrefix(s, "((") && strings.HasSuffix(s, "))") {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
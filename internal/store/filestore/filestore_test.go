@@ -0,0 +1,628 @@
+package filestore
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		desc    string
+		add     []string
+		delete  []string
+		readd   bool
+		replace [][2]string
+		want    []string
+	}{
+		{
+			desc: "facts",
+			add: []string{
+				"(Ozan, age, 24)",
+				`(Ozan, name, "Ozan, \"the\" (first)")`,
+				"(Ufuk, knows, (Ozan, knows, CS))",
+				"((Ozan, knows, CS), approvedBy, METU)",
+			},
+			want: []string{
+				"((Ozan, knows, CS), approvedBy, METU)",
+				"(Ozan, age, 24)",
+				`(Ozan, name, "Ozan, \"the\" (first)")`,
+				"(Ufuk, knows, (Ozan, knows, CS))",
+			},
+		},
+		{
+			desc: "typed literals",
+			add:  []string{"(Ozan, born, 1999-05-04)", "(Ozan, joined, 2023-07-08T14:30:00+03:00)", "(Ozan, active, true)", "(Ozan, height, 1.83)"},
+			want: []string{
+				"(Ozan, active, true)",
+				"(Ozan, born, 1999-05-04)",
+				"(Ozan, height, 1.83)",
+				"(Ozan, joined, 2023-07-08T14:30:00+03:00)",
+			},
+		},
+		{
+			desc: "annotated strings",
+			add:  []string{`(Ozan, label, "Ozan"@en)`, `(Ozan, label, "Ozan"@tr)`, `(Ozan, code, "42, (x)"^^<http://example.org/types#code>)`},
+			want: []string{
+				`(Ozan, code, "42, (x)"^^<http://example.org/types#code>)`,
+				`(Ozan, label, "Ozan"@en)`,
+				`(Ozan, label, "Ozan"@tr)`,
+			},
+		},
+		{
+			desc: "lossless numbers",
+			add:  []string{"(Ozan, value, 0.000000001)", "(Ozan, value, 0.0)", "(Ozan, value, 123.4567891)"},
+			want: []string{"(Ozan, value, 0.0)", "(Ozan, value, 123.4567891)", "(Ozan, value, 1e-09)"},
+		},
+		{
+			desc: "IRIs",
+			add:  []string{"(<http://example.org/hr#Ozan>, <http://example.org/hr#knows>, (Ozan, knows, CS))"},
+			want: []string{"(<http://example.org/hr#Ozan>, <http://example.org/hr#knows>, (Ozan, knows, CS))"},
+		},
+		{
+			desc: "IRIs with commas and parentheses",
+			add: []string{
+				"(<http://example.org/a,b>, <http://example.org/p(1>, <http://example.org/c),d>)",
+				"((<http://example.org/a,b>, knows, CS), approvedBy, <http://example.org/x,(y>)",
+			},
+			want: []string{
+				"((<http://example.org/a,b>, knows, CS), approvedBy, <http://example.org/x,(y>)",
+				"(<http://example.org/a,b>, <http://example.org/p(1>, <http://example.org/c),d>)",
+			},
+		},
+		{
+			desc:   "deleted facts do not reappear",
+			add:    []string{"(Ozan, age, 24)", "(Ozan, is, Person)"},
+			delete: []string{"(Ozan, age, 24)"},
+			want:   []string{"(Ozan, is, Person)"},
+		},
+		{
+			desc:    "replaced facts",
+			add:     []string{"(Ozan, knows, CS)", "((Ozan, knows, CS), approvedBy, METU)"},
+			replace: [][2]string{{"(Ozan, knows, CS)", "(Ozan, knows, AI)"}},
+			want:    []string{"((Ozan, knows, AI), approvedBy, METU)", "(Ozan, knows, AI)"},
+		},
+		{
+			desc:   "re-added facts reappear",
+			add:    []string{"(Ozan, age, 24)"},
+			delete: []string{"(Ozan, age, 24)"},
+			readd:  true,
+			want:   []string{"(Ozan, age, 24)"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			path := filepath.Join(t.TempDir(), "store.db")
+			fs, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			for _, f := range tc.add {
+				if err := fs.Add(mustParseFact(t, p, f)); err != nil {
+					t.Fatalf("failed to add fact: %v", err)
+				}
+			}
+			fs.Sync()
+			for _, f := range tc.delete {
+				if err := fs.Delete(mustParseFact(t, p, f)); err != nil {
+					t.Fatalf("failed to delete fact: %v", err)
+				}
+			}
+			for _, r := range tc.replace {
+				if err := fs.Replace(mustParseFact(t, p, r[0]), mustParseFact(t, p, r[1])); err != nil {
+					t.Fatalf("failed to replace fact: %v", err)
+				}
+			}
+			if tc.readd {
+				fs.Sync()
+				for _, f := range tc.add {
+					if err := fs.Add(mustParseFact(t, p, f)); err != nil {
+						t.Fatalf("failed to add fact: %v", err)
+					}
+				}
+			}
+			if err := fs.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+
+			reloaded, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to reload store: %v", err)
+			}
+			defer reloaded.Close()
+			facts, err := reloaded.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			got := []string{}
+			for _, f := range facts {
+				got = append(got, f.Pretty())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected facts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNonFiniteNumbers(t *testing.T) {
+	p := parser.New()
+	number := func(v float64) *parser.Fact {
+		return &parser.Fact{Subject: ptrutils.Ptr("Ozan"), Predicate: "value", Object: parser.NumberObject{Value: v}}
+	}
+	tests := []struct {
+		desc  string
+		facts []*parser.Fact
+	}{
+		{desc: "NaN", facts: []*parser.Fact{number(math.NaN())}},
+		{desc: "positive infinity", facts: []*parser.Fact{number(math.Inf(1))}},
+		{desc: "negative infinity", facts: []*parser.Fact{number(math.Inf(-1))}},
+		{
+			desc:  "nested fact",
+			facts: []*parser.Fact{{SubjectFact: number(math.NaN()), Predicate: "approvedBy", Object: parser.SubjectObject{Value: "METU"}}},
+		},
+		{
+			desc:  "among finite facts",
+			facts: []*parser.Fact{number(1.5), number(math.Inf(1))},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "store.db")
+			fs, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			if err := fs.AddAll(tc.facts); err == nil {
+				t.Errorf("expected an error adding the facts, got none")
+			}
+			if err := fs.AddToGraph("HR", tc.facts); err == nil {
+				t.Errorf("expected an error adding the facts to a graph, got none")
+			}
+			if err := fs.Add(mustParseFact(t, p, "(Ozan, value, 1.0)")); err != nil {
+				t.Fatalf("failed to add fact: %v", err)
+			}
+			if err := fs.Replace(mustParseFact(t, p, "(Ozan, value, 1.0)"), tc.facts[len(tc.facts)-1]); err == nil {
+				t.Errorf("expected an error replacing the fact, got none")
+			}
+			if err := fs.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+
+			reloaded, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to reload store: %v", err)
+			}
+			defer reloaded.Close()
+			facts, err := reloaded.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			got := []string{}
+			for _, f := range facts {
+				got = append(got, f.Pretty())
+			}
+			if diff := cmp.Diff([]string{"(Ozan, value, 1.0)"}, got); diff != "" {
+				t.Errorf("unexpected facts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func mustParseFact(t *testing.T, p *parser.Parser, fact string) *parser.Fact {
+	t.Helper()
+	e, err := p.ParseLine(fact)
+	if err != nil {
+		t.Fatalf("failed to parse fact %q: %v", fact, err)
+	}
+	return e.Fact
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		desc    string
+		facts   []string
+		rules   []string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			desc:  "derived facts",
+			facts: []string{"(Ali, parentOf, Veli)", "(Veli, parentOf, Ayse)"},
+			rules: []string{"(?x, grandparentOf, ?z) :- (?x, parentOf, !y) -> (!y, parentOf, ?z)"},
+			query: "(?x, grandparentOf, ?z)",
+			want:  []string{"(Ali, grandparentOf, Ayse)"},
+		},
+		{
+			desc:  "recursive rules",
+			facts: []string{"(Ali, parentOf, Veli)", "(Veli, parentOf, Ayse)", "(Ayse, parentOf, Can)"},
+			rules: []string{
+				"(?x, ancestorOf, ?y) :- (?x, parentOf, ?y)",
+				"(?x, ancestorOf, ?z) :- (?x, parentOf, !y) -> (!y, ancestorOf, ?z)",
+			},
+			query: "(?x, ancestorOf, Can)",
+			want:  []string{"(Ali, ancestorOf, Can)", "(Ayse, ancestorOf, Can)", "(Veli, ancestorOf, Can)"},
+		},
+		{
+			desc:  "nested fact heads",
+			facts: []string{"(Ezgi, says, Rain)"},
+			rules: []string{"(?x, believes, (?x, saw, ?y)) :- (?x, says, ?y)"},
+			query: "(Ezgi, believes, ?f)",
+			want:  []string{"(Ezgi, believes, (Ezgi, saw, Rain))"},
+		},
+		{
+			desc:  "union bodies",
+			facts: []string{"(Ali, motherOf, Veli)", "(Ayse, fatherOf, Veli)"},
+			rules: []string{"(?x, parentOf, ?y) :- { (?x, motherOf, ?y) | (?x, fatherOf, ?y) }"},
+			query: "(?x, parentOf, Veli)",
+			want:  []string{"(Ali, parentOf, Veli)", "(Ayse, parentOf, Veli)"},
+		},
+		{
+			desc:  "computed heads",
+			facts: []string{"(Ali, age, 2)"},
+			rules: []string{"(?x, ageInMonths, ?m) :- (?x, age, ?a) -> bind ?a * 12 as ?m"},
+			query: "(?x, ageInMonths, ?m)",
+			want:  []string{"(Ali, ageInMonths, 24)"},
+		},
+		{
+			desc:    "rules without a fixpoint",
+			facts:   []string{"(Ozan, knows, CS)"},
+			rules:   []string{"(?x, knows, (?x, knows, ?y)) :- (?x, knows, ?y)"},
+			query:   "(?x, knows, ?y)",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			fs, err := New()
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			for _, f := range tc.facts {
+				if err := fs.Add(mustParseFact(t, p, f)); err != nil {
+					t.Fatalf("failed to add fact: %v", err)
+				}
+			}
+			for _, r := range tc.rules {
+				e, err := p.ParseLine(r)
+				if err != nil {
+					t.Fatalf("failed to parse rule %q: %v", r, err)
+				}
+				rule, err := store.RuleFromAST(e.Rule)
+				if err != nil {
+					t.Fatalf("failed to convert rule: %v", err)
+				}
+				if err := fs.AddRule(rule); err != nil {
+					t.Fatalf("failed to add rule: %v", err)
+				}
+			}
+			e, err := p.ParseLine(tc.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+			q, err := store.QueryFromAST(e.Query)
+			if err != nil {
+				t.Fatalf("failed to convert query: %v", err)
+			}
+			facts, err := fs.Get(q)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			got := []string{}
+			for _, f := range facts {
+				got = append(got, f.Pretty())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected facts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestDeleteBeforeClose deletes a fact written by an earlier session, leaving
+// the tombstone to be flushed by Close.
+func TestDeleteBeforeClose(t *testing.T) {
+	p := parser.New()
+	path := filepath.Join(t.TempDir(), "store.db")
+	open := func() *FileStore {
+		t.Helper()
+		fs, err := New(WithPersistentFile(path))
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		return fs
+	}
+
+	fs := open()
+	if err := fs.Add(mustParseFact(t, p, "(Ozan, is, Person)")); err != nil {
+		t.Fatalf("failed to add fact: %v", err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	fs = open()
+	if err := fs.Delete(mustParseFact(t, p, "(Ozan, is, Person)")); err != nil {
+		t.Fatalf("failed to delete fact: %v", err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	fs = open()
+	defer fs.Close()
+	facts, err := fs.Get(&store.Query{})
+	if err != nil {
+		t.Fatalf("failed to get facts: %v", err)
+	}
+	if len(facts) != 0 {
+		t.Errorf("deleted fact reappeared: %v", facts)
+	}
+}
+
+// TestRekeyedRecords loads records written while numbers were written with six
+// decimals, whose IDs are not the hashes of their facts anymore.
+func TestRekeyedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	records := "(1, Ozan, height, 2, 1.830000)\n(2, Ozan, age, 2, 24.000000)\n-(2)\n"
+	if err := os.WriteFile(path, []byte(records), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := New(WithPersistentFile(path))
+	if err != nil {
+		t.Fatalf("failed to load store: %v", err)
+	}
+	defer fs.Close()
+	p := parser.New()
+	height := mustParseFact(t, p, "(Ozan, height, 1.83)")
+	got, err := fs.GetByID(store.FactHash(height))
+	if err != nil {
+		t.Fatalf("fs.GetByID() error = %v", err)
+	}
+	if got.Pretty() != height.Pretty() {
+		t.Errorf("fs.GetByID() = %s, want %s", got.Pretty(), height.Pretty())
+	}
+	if _, err := fs.GetByID(store.FactHash(mustParseFact(t, p, "(Ozan, age, 24.0)"))); err == nil {
+		t.Errorf("fs.GetByID() of a deleted fact succeeded")
+	}
+}
+
+func TestGetByID(t *testing.T) {
+	tests := []struct {
+		desc    string
+		fact    string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "asserted fact",
+			fact: "(Ali, parentOf, Veli)",
+			want: "(Ali, parentOf, Veli)",
+		},
+		{
+			desc: "derived fact",
+			fact: "(Ali, ancestorOf, Veli)",
+			want: "(Ali, ancestorOf, Veli)",
+		},
+		{
+			desc:    "unknown fact",
+			fact:    "(Veli, parentOf, Ali)",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			fs, err := New()
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			if err := fs.Add(mustParseFact(t, p, "(Ali, parentOf, Veli)")); err != nil {
+				t.Fatalf("failed to add fact: %v", err)
+			}
+			e, err := p.ParseLine("(?x, ancestorOf, ?y) :- (?x, parentOf, ?y)")
+			if err != nil {
+				t.Fatalf("failed to parse rule: %v", err)
+			}
+			rule, err := store.RuleFromAST(e.Rule)
+			if err != nil {
+				t.Fatalf("failed to convert rule: %v", err)
+			}
+			if err := fs.AddRule(rule); err != nil {
+				t.Fatalf("failed to add rule: %v", err)
+			}
+			got, err := fs.GetByID(store.FactHash(mustParseFact(t, p, tc.fact)))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get fact: %v", err)
+			}
+			if got.Pretty() != tc.want {
+				t.Errorf("fs.GetByID() = %s, want %s", got.Pretty(), tc.want)
+			}
+		})
+	}
+}
+
+func TestGraphs(t *testing.T) {
+	type graphFacts struct {
+		graph string
+		facts []string
+	}
+	tests := []struct {
+		desc    string
+		add     []graphFacts
+		drop    []string
+		reload  []graphFacts
+		replace [][2]string
+		// want holds the facts of every graph, and of the whole store under
+		// the empty graph.
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			desc: "facts in several graphs",
+			add: []graphFacts{
+				{store.DefaultGraph, []string{"(Ozan, is, Person)"}},
+				{"HR", []string{"(Ozan, is, Person)", "(Ozan, age, 24)"}},
+				{"CRM", []string{"(Ufuk, is, Person)"}},
+			},
+			want: map[string][]string{
+				"":                 {"(Ozan, age, 24)", "(Ozan, is, Person)", "(Ufuk, is, Person)"},
+				store.DefaultGraph: {"(Ozan, is, Person)"},
+				"HR":               {"(Ozan, age, 24)", "(Ozan, is, Person)"},
+				"CRM":              {"(Ufuk, is, Person)"},
+			},
+		},
+		{
+			desc: "dropped graphs",
+			add: []graphFacts{
+				{store.DefaultGraph, []string{"(Ozan, is, Person)"}},
+				{"HR", []string{"(Ozan, is, Person)", "(Ozan, age, 24)"}},
+			},
+			drop: []string{"HR"},
+			want: map[string][]string{
+				"":                 {"(Ozan, is, Person)"},
+				store.DefaultGraph: {"(Ozan, is, Person)"},
+				"HR":               {},
+			},
+		},
+		{
+			desc: "reloaded graphs",
+			add: []graphFacts{
+				{"HR", []string{"(Ozan, is, Person)", "(Ozan, age, 24)"}},
+				{"CRM", []string{"(Ozan, is, Person)"}},
+			},
+			reload: []graphFacts{{"HR", []string{"(Ozan, age, 25)", "(Ufuk, age, 30)"}}},
+			want: map[string][]string{
+				"":    {"(Ozan, age, 25)", "(Ozan, is, Person)", "(Ufuk, age, 30)"},
+				"HR":  {"(Ozan, age, 25)", "(Ufuk, age, 30)"},
+				"CRM": {"(Ozan, is, Person)"},
+			},
+		},
+		{
+			desc: "replaced facts keep their graphs",
+			add: []graphFacts{
+				{"HR", []string{"(Ozan, knows, CS)", "((Ozan, knows, CS), approvedBy, METU)"}},
+			},
+			replace: [][2]string{{"(Ozan, knows, CS)", "(Ozan, knows, AI)"}},
+			want: map[string][]string{
+				"HR":               {"((Ozan, knows, AI), approvedBy, METU)", "(Ozan, knows, AI)"},
+				store.DefaultGraph: {},
+			},
+		},
+		{
+			desc: "graphs named by IRIs",
+			add: []graphFacts{
+				{"<http://example.org/graphs/a,]b>", []string{"(<http://example.org/a,b>, is, Person)"}},
+			},
+			want: map[string][]string{
+				"<http://example.org/graphs/a,]b>": {"(<http://example.org/a,b>, is, Person)"},
+			},
+		},
+		{
+			desc:    "unknown graphs",
+			drop:    []string{"HR"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			path := filepath.Join(t.TempDir(), "store.db")
+			fs, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			parseFacts := func(facts []string) []*parser.Fact {
+				ts := []*parser.Fact{}
+				for _, f := range facts {
+					ts = append(ts, mustParseFact(t, p, f))
+				}
+				return ts
+			}
+			for _, a := range tc.add {
+				if err := fs.AddToGraph(a.graph, parseFacts(a.facts)); err != nil {
+					t.Fatalf("failed to add facts: %v", err)
+				}
+			}
+			fs.Sync()
+			for _, g := range tc.drop {
+				err := fs.DropGraph(g)
+				if tc.wantErr {
+					if err == nil {
+						t.Fatalf("expected an error, got none")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("failed to drop graph: %v", err)
+				}
+			}
+			for _, r := range tc.reload {
+				if err := fs.ReloadGraph(r.graph, parseFacts(r.facts)); err != nil {
+					t.Fatalf("failed to reload graph: %v", err)
+				}
+			}
+			for _, r := range tc.replace {
+				if err := fs.Replace(mustParseFact(t, p, r[0]), mustParseFact(t, p, r[1])); err != nil {
+					t.Fatalf("failed to replace fact: %v", err)
+				}
+			}
+			if err := fs.Close(); err != nil {
+				t.Fatalf("failed to close store: %v", err)
+			}
+
+			reloaded, err := New(WithPersistentFile(path))
+			if err != nil {
+				t.Fatalf("failed to reload store: %v", err)
+			}
+			defer reloaded.Close()
+			for graph, want := range tc.want {
+				var r store.Reader = reloaded
+				if graph != "" {
+					r = reloaded.InGraphs([]string{graph})
+				}
+				facts, err := r.Get(&store.Query{})
+				if err != nil {
+					t.Fatalf("failed to get facts: %v", err)
+				}
+				got := []string{}
+				for _, f := range facts {
+					got = append(got, f.Pretty())
+				}
+				sort.Strings(got)
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("unexpected facts of graph %q (-want +got):\n%s", graph, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package filestore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+)
+
+// AddToGraph adds the facts to the graph, along with the other graphs they
+// are in.
+func (fs *FileStore) AddToGraph(graph string, ts []*parser.Fact) error {
+	if err := store.CheckFacts(ts...); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		fs.add(t, graph)
+	}
+	fs.invalidateDerived()
+	return nil
+}
+
+// DropGraph removes the facts of the graph, except the ones that are in other
+// graphs as well, which only leave the graph.
+func (fs *FileStore) DropGraph(graph string) error {
+	if fs.dropGraph(graph) == 0 {
+		return fmt.Errorf("graph %s not found in store", graph)
+	}
+	fs.invalidateDerived()
+	return nil
+}
+
+// ReloadGraph replaces the facts of the graph with the given ones. The graph
+// does not have to exist yet.
+func (fs *FileStore) ReloadGraph(graph string, ts []*parser.Fact) error {
+	if err := store.CheckFacts(ts...); err != nil {
+		return err
+	}
+	fs.dropGraph(graph)
+	return fs.AddToGraph(graph, ts)
+}
+
+// dropGraph removes the facts from the graph, and from the store if it was
+// their only graph. It returns the number of facts removed from the graph.
+func (fs *FileStore) dropGraph(graph string) int {
+	fs.graphsMu.Lock()
+	defer fs.graphsMu.Unlock()
+	dropped := 0
+	for id, graphs := range fs.graphs {
+		if !graphs[graph] {
+			continue
+		}
+		delete(graphs, graph)
+		if len(graphs) == 0 {
+			delete(fs.graphs, id)
+			fs.store.Delete(id)
+		}
+		fs.idBuffer.Store(id, true)
+		dropped++
+	}
+	return dropped
+}
+
+// InGraphs returns a reader of the asserted facts of the graphs.
+func (fs *FileStore) InGraphs(graphs []string) store.Reader {
+	v := &graphView{fs: fs, graphs: map[string]bool{}}
+	for _, g := range graphs {
+		v.graphs[g] = true
+	}
+	return v
+}
+
+func (fs *FileStore) addGraph(id uint32, graph string) {
+	fs.graphsMu.Lock()
+	defer fs.graphsMu.Unlock()
+	if fs.graphs[id] == nil {
+		fs.graphs[id] = map[string]bool{}
+	}
+	fs.graphs[id][graph] = true
+}
+
+// graphsOf returns the graphs of the fact with the ID, in order.
+func (fs *FileStore) graphsOf(id uint32) []string {
+	fs.graphsMu.Lock()
+	defer fs.graphsMu.Unlock()
+	graphs := make([]string, 0, len(fs.graphs[id]))
+	for g := range fs.graphs[id] {
+		graphs = append(graphs, g)
+	}
+	sort.Strings(graphs)
+	return graphs
+}
+
+// graphView reads the asserted facts of some graphs of the store.
+type graphView struct {
+	fs     *FileStore
+	graphs map[string]bool
+}
+
+func (v *graphView) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
+	trs := map[uint32]*parser.Fact{}
+	for id, t := range v.fs.getAsserted(q) {
+		for _, g := range v.fs.graphsOf(id) {
+			if v.graphs[g] {
+				trs[id] = t
+				break
+			}
+		}
+	}
+	return trs, nil
+}
+
+// InGraphs returns a reader of the asserted facts of the graphs, so that
+// queries naming their graphs read them whatever the graphs of the view.
+func (v *graphView) InGraphs(graphs []string) store.Reader {
+	return v.fs.InGraphs(graphs)
+}
+
+// graphEncode prefixes the record of a fact in a named graph with the graph.
+func graphEncode(graph string, record []byte) []byte {
+	if graph == store.DefaultGraph {
+		return record
+	}
+	return append([]byte(fmt.Sprintf("[%s] ", graph)), record...)
+}
+
+// graphDecode splits a record written by graphEncode into the graph and the
+// record of the fact.
+func graphDecode(record string) (string, string, error) {
+	if !strings.HasPrefix(record, "[") {
+		return store.DefaultGraph, record, nil
+	}
+	// Graphs named by IRIs may contain ']', but not '>'.
+	from := 1
+	if strings.HasPrefix(record, "[<") {
+		if from = strings.Index(record, ">"); from < 0 {
+			return "", "", fmt.Errorf("malformed record graph: %s", record)
+		}
+	}
+	end := strings.Index(record[from:], "]")
+	if end < 0 {
+		return "", "", fmt.Errorf("malformed record graph: %s", record)
+	}
+	end += from
+	return record[1:end], strings.TrimSpace(record[end+1:]), nil
+}
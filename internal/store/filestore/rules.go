@@ -0,0 +1,95 @@
+package filestore
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+)
+
+const (
+	// maxRuleIterations bounds the forward chaining of rules whose heads keep
+	// nesting facts, which never reach a fixpoint.
+	maxRuleIterations = 100
+)
+
+// AddRule registers the rule, so that the facts it derives are answered by
+// Get like asserted ones. Rules are not persisted.
+func (fs *FileStore) AddRule(r *store.Rule) error {
+	fs.rulesMu.Lock()
+	defer fs.rulesMu.Unlock()
+	fs.rules = append(fs.rules, r)
+	fs.derived = nil
+	return nil
+}
+
+// invalidateDerived drops the derived facts, so that they are derived again
+// from the current facts on the next Get.
+func (fs *FileStore) invalidateDerived() {
+	fs.rulesMu.Lock()
+	defer fs.rulesMu.Unlock()
+	fs.derived = nil
+}
+
+// derivedFacts returns the facts derived by the rules that are not asserted,
+// forward chaining the rules until a fixpoint is reached.
+func (fs *FileStore) derivedFacts() (map[uint32]*parser.Fact, error) {
+	fs.rulesMu.Lock()
+	defer fs.rulesMu.Unlock()
+	if fs.derived != nil || len(fs.rules) == 0 {
+		return fs.derived, nil
+	}
+
+	view := &derivedView{
+		fs:      fs,
+		derived: map[uint32]*parser.Fact{},
+	}
+	for i := 0; ; i++ {
+		if i == maxRuleIterations {
+			return nil, fmt.Errorf("rules did not reach a fixpoint in %d iterations", maxRuleIterations)
+		}
+		changed := false
+		for _, r := range fs.rules {
+			rows, err := store.Solve(view, r.Body)
+			if err != nil {
+				return nil, fmt.Errorf("store.Solve(%s): %v", r.Pretty(), err)
+			}
+			for _, row := range rows {
+				t, err := r.Head.Instantiate(row.Bindings)
+				if err != nil {
+					return nil, fmt.Errorf("r.Head.Instantiate(%s): %v", r.Pretty(), err)
+				}
+				h := store.FactHash(t)
+				if _, ok := fs.store.Load(h); ok {
+					continue
+				}
+				if _, ok := view.derived[h]; ok {
+					continue
+				}
+				view.derived[h] = t
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	fs.derived = view.derived
+	return fs.derived, nil
+}
+
+// derivedView reads the asserted facts along with the facts derived so far.
+type derivedView struct {
+	fs      *FileStore
+	derived map[uint32]*parser.Fact
+}
+
+func (v *derivedView) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
+	trs := v.fs.getAsserted(q)
+	for id, t := range v.derived {
+		if q.Matches(t) {
+			trs[id] = t.Copy()
+		}
+	}
+	return trs, nil
+}
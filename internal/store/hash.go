@@ -0,0 +1,30 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/zhenjl/cityhash"
+)
+
+// FactHash returns the ID of the fact, the hash of its content. Stores key
+// their facts by it, and the facts quoted in other facts are identified by it
+// as well.
+func FactHash(t *parser.Fact) uint32 {
+	var s string
+	if t.Subject != nil {
+		if t.Object != nil {
+			s = fmt.Sprintf("s:%q|p:%q|o:%d:%q", *t.Subject, t.Predicate, t.Object.Kind(), t.Object.String())
+		} else {
+			s = fmt.Sprintf("s:%q|p:%q|o:(%s)", *t.Subject, t.Predicate, t.ObjectFact.Pretty())
+		}
+	} else {
+		if t.Object != nil {
+			s = fmt.Sprintf("s:(%s)|p:%q|o:%d:%q", t.SubjectFact.Pretty(), t.Predicate, t.Object.Kind(), t.Object.String())
+		} else {
+			s = fmt.Sprintf("s:(%s)|p:%q|o:(%s)", t.SubjectFact.Pretty(), t.Predicate, t.ObjectFact.Pretty())
+		}
+	}
+	b := []byte(s)
+	return cityhash.CityHash32(b, uint32(len(b)))
+}
@@ -0,0 +1,291 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+type AggregateFunction string
+
+const (
+	AggregateCount AggregateFunction = "count"
+	AggregateSum   AggregateFunction = "sum"
+	AggregateAvg   AggregateFunction = "avg"
+	AggregateMin   AggregateFunction = "min"
+	AggregateMax   AggregateFunction = "max"
+)
+
+const (
+	// allRowsVar is the aggregate variable that stands for every solution, as
+	// in count(*).
+	allRowsVar = "*"
+)
+
+// Aggregate summarises the values bound to Variable in a group of solutions
+// into the variable As.
+type Aggregate struct {
+	Function AggregateFunction
+	Variable string
+	As       string
+}
+
+// Modifiers post-process the solutions of a query.
+type Modifiers struct {
+	Aggregates []*Aggregate
+	GroupBy    []string
+	OrderBy    []*OrderKey
+	Limit      *int
+	Offset     *int
+	// Scope is empty for the default scope, the asserted facts.
+	Scope Scope
+	// From restricts the query to the facts of the graphs, if not empty.
+	From []string
+}
+
+// OrderKey sorts solutions by the value bound to Variable.
+type OrderKey struct {
+	Variable   string
+	Descending bool
+}
+
+func ModifiersFromAST(m *parser.Modifiers) (*Modifiers, error) {
+	if m == nil {
+		return nil, nil
+	}
+	mm := &Modifiers{
+		GroupBy: append([]string{}, m.GroupBy...),
+		Limit:   ptrutils.PtrFromPtr(m.Limit),
+		Offset:  ptrutils.PtrFromPtr(m.Offset),
+	}
+	if mm.Limit != nil && *mm.Limit < 0 {
+		return nil, fmt.Errorf("limit can not be negative: %d", *mm.Limit)
+	}
+	if mm.Offset != nil && *mm.Offset < 0 {
+		return nil, fmt.Errorf("offset can not be negative: %d", *mm.Offset)
+	}
+	if m.Scope != nil {
+		mm.Scope = Scope(*m.Scope)
+	}
+	mm.From = append(mm.From, m.From...)
+	for _, k := range m.OrderBy {
+		mm.OrderBy = append(mm.OrderBy, &OrderKey{
+			Variable:   k.Variable,
+			Descending: k.Descending,
+		})
+	}
+	for _, a := range m.Aggregates {
+		f := AggregateFunction(a.Function)
+		if a.Variable == allRowsVar && f != AggregateCount {
+			return nil, fmt.Errorf("%s can not be applied to %s", f, allRowsVar)
+		}
+		mm.Aggregates = append(mm.Aggregates, &Aggregate{
+			Function: f,
+			Variable: a.Variable,
+			As:       a.As,
+		})
+	}
+	return mm, nil
+}
+
+// Variables returns the returned variables of the aggregated solutions: the
+// returned grouping variables followed by the aggregate results.
+func (m *Modifiers) Variables() []string {
+	vars := []string{}
+	for _, v := range m.GroupBy {
+		if IsReturnedVar(v) {
+			vars = append(vars, v)
+		}
+	}
+	for _, a := range m.Aggregates {
+		vars = append(vars, a.As)
+	}
+	return vars
+}
+
+// aggregate groups the rows by the grouping variables and summarises every
+// group into a single row. Without grouping variables, all rows form a single
+// group, even if there are none.
+func (m *Modifiers) aggregate(rows []*Row) ([]*Row, error) {
+	keys := []string{}
+	groups := map[string][]*Row{}
+	for _, r := range rows {
+		var sb strings.Builder
+		for _, v := range m.GroupBy {
+			if o, ok := r.Bindings[v]; ok {
+				sb.WriteString(fmt.Sprintf("%d:%s", o.Kind, o.String()))
+			}
+			sb.WriteRune('|')
+		}
+		k := sb.String()
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	if len(m.GroupBy) == 0 && len(rows) == 0 {
+		keys = append(keys, "")
+	}
+
+	aggregated := []*Row{}
+	for _, k := range keys {
+		group := groups[k]
+		b := Bindings{}
+		if len(group) > 0 {
+			b = group[0].Bindings.Project(m.GroupBy)
+		}
+		for _, a := range m.Aggregates {
+			o, err := a.apply(group)
+			if err != nil {
+				return nil, err
+			}
+			if o != nil {
+				b[a.As] = o
+			}
+		}
+		aggregated = append(aggregated, &Row{Bindings: b})
+	}
+	return aggregated, nil
+}
+
+// apply summarises the group. It returns nil if the aggregate has no value,
+// e.g. the minimum of an empty group.
+func (a *Aggregate) apply(group []*Row) (*Object, error) {
+	values := []*Object{}
+	for _, r := range group {
+		if a.Variable == allRowsVar {
+			values = append(values, nil)
+		} else if o, ok := r.Bindings[a.Variable]; ok {
+			values = append(values, o)
+		}
+	}
+	switch a.Function {
+	case AggregateCount:
+		return intObject(int64(len(values))), nil
+	case AggregateSum, AggregateAvg:
+		sum := intObject(0)
+		for _, o := range values {
+			if !isNumberKind(o.Kind) {
+				return nil, fmt.Errorf("%s(%s): %s is not a number", a.Function, a.Variable, o.String())
+			}
+			var err error
+			if sum, err = addNumbers(sum, o); err != nil {
+				return nil, fmt.Errorf("%s(%s): %v", a.Function, a.Variable, err)
+			}
+		}
+		if a.Function == AggregateSum {
+			return sum, nil
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return floatObject(floatValue(sum) / float64(len(values))), nil
+	case AggregateMin, AggregateMax:
+		var best *Object
+		for _, o := range values {
+			if best == nil {
+				best = o
+				continue
+			}
+			cmp, ok := compareObjects(o, best)
+			if !ok {
+				return nil, fmt.Errorf("%s(%s): %s and %s are not comparable", a.Function, a.Variable, o.String(), best.String())
+			}
+			if (a.Function == AggregateMin && cmp < 0) || (a.Function == AggregateMax && cmp > 0) {
+				best = o
+			}
+		}
+		return best, nil
+	}
+	return nil, fmt.Errorf("unknown aggregate function: %s", a.Function)
+}
+
+// order sorts the rows by the order keys. Rows that compare equal keep their
+// relative order.
+func (m *Modifiers) order(rows []*Row) {
+	if len(m.OrderBy) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, k := range m.OrderBy {
+			cmp := orderObjects(rows[i].Bindings[k.Variable], rows[j].Bindings[k.Variable])
+			if cmp == 0 {
+				continue
+			}
+			if k.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// slice applies the offset and the limit to the rows.
+func (m *Modifiers) slice(rows []*Row) []*Row {
+	if m.Offset != nil {
+		if *m.Offset >= len(rows) {
+			return []*Row{}
+		}
+		rows = rows[*m.Offset:]
+	}
+	if m.Limit != nil && *m.Limit < len(rows) {
+		rows = rows[:*m.Limit]
+	}
+	return rows
+}
+
+func (m *Modifiers) Pretty() string {
+	clauses := []string{}
+	if len(m.Aggregates) > 0 {
+		aggregates := []string{}
+		for _, a := range m.Aggregates {
+			aggregates = append(aggregates, fmt.Sprintf("%s(%s) as %s", a.Function, a.Variable, a.As))
+		}
+		clauses = append(clauses, "aggregate "+strings.Join(aggregates, ", "))
+	}
+	if len(m.GroupBy) > 0 {
+		clauses = append(clauses, "group by "+strings.Join(m.GroupBy, ", "))
+	}
+	if len(m.OrderBy) > 0 {
+		keys := []string{}
+		for _, k := range m.OrderBy {
+			if k.Descending {
+				keys = append(keys, k.Variable+" desc")
+			} else {
+				keys = append(keys, k.Variable)
+			}
+		}
+		clauses = append(clauses, "order by "+strings.Join(keys, ", "))
+	}
+	if m.Limit != nil {
+		clauses = append(clauses, fmt.Sprintf("limit %d", *m.Limit))
+	}
+	if m.Offset != nil {
+		clauses = append(clauses, fmt.Sprintf("offset %d", *m.Offset))
+	}
+	if m.Scope != "" {
+		clauses = append(clauses, fmt.Sprintf("match %s", m.Scope))
+	}
+	if len(m.From) > 0 {
+		clauses = append(clauses, "from "+strings.Join(m.From, ", "))
+	}
+	return strings.Join(clauses, " ")
+}
+
+func floatObject(f float64) *Object {
+	return &Object{FloatValue: ptrutils.Ptr(f), Kind: ObjectKindFloat}
+}
+
+func intObject(i int64) *Object {
+	return &Object{IntValue: ptrutils.Ptr(i), Kind: ObjectKindInt}
+}
+
+// timeObject returns a date or a timestamp, depending on the kind.
+func timeObject(t time.Time, kind ObjectKind) *Object {
+	return &Object{TimeValue: &t, Kind: kind}
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+// PredicatePath matches a subject and an object connected by any of its
+// alternative steps.
+type PredicatePath struct {
+	Alternatives []*PathStep
+}
+
+// PathStep connects a subject and an object through a chain of Min to Max
+// facts with the predicate, followed from object to subject if Inverse. A nil
+// Max leaves the chain length unbounded.
+type PathStep struct {
+	Predicate string
+	Inverse   bool
+	Min       int
+	Max       *int
+}
+
+func PathFromAST(p *parser.PredicatePath) (*PredicatePath, error) {
+	pp := &PredicatePath{}
+	for _, s := range p.Alternatives {
+		step := &PathStep{
+			Predicate: s.Predicate,
+			Inverse:   s.Inverse,
+			Min:       1,
+			Max:       ptrutils.Ptr(1),
+		}
+		switch {
+		case s.Modifier != nil && *s.Modifier == "+":
+			step.Max = nil
+		case s.Modifier != nil && *s.Modifier == "*":
+			step.Min, step.Max = 0, nil
+		case s.Min != nil:
+			step.Min, step.Max = *s.Min, ptrutils.PtrFromPtr(s.Min)
+			if s.Max != nil {
+				step.Max = ptrutils.PtrFromPtr(s.Max)
+			}
+		}
+		if step.Min < 0 || (step.Max != nil && *step.Max < step.Min) {
+			return nil, fmt.Errorf("invalid repetition in path step: %s", s.Pretty())
+		}
+		pp.Alternatives = append(pp.Alternatives, step)
+	}
+	return pp, nil
+}
+
+func (p *PredicatePath) Pretty() string {
+	steps := []string{}
+	for _, s := range p.Alternatives {
+		var sb strings.Builder
+		if s.Inverse {
+			sb.WriteRune('^')
+		}
+		sb.WriteString(s.Predicate)
+		switch {
+		case s.Min == 1 && s.Max == nil:
+			sb.WriteRune('+')
+		case s.Min == 0 && s.Max == nil:
+			sb.WriteRune('*')
+		case s.Max == nil:
+			sb.WriteString(fmt.Sprintf("{%d,}", s.Min))
+		case s.Min == *s.Max && s.Min != 1:
+			sb.WriteString(fmt.Sprintf("{%d}", s.Min))
+		case s.Min != *s.Max:
+			sb.WriteString(fmt.Sprintf("{%d,%d}", s.Min, *s.Max))
+		}
+		steps = append(steps, sb.String())
+	}
+	return strings.Join(steps, "|")
+}
+
+// pathGraph holds the edges of the predicates of a path, keyed by the key of
+// the node they start from.
+type pathGraph struct {
+	forward  map[string]map[string][]*Object
+	backward map[string]map[string][]*Object
+	// nodes holds the nodes with an edge of any of the predicates, in the
+	// order of the facts they appear in.
+	nodes []*Object
+}
+
+func newPathGraph(s Reader, p *PredicatePath) (*pathGraph, error) {
+	g := &pathGraph{
+		forward:  map[string]map[string][]*Object{},
+		backward: map[string]map[string][]*Object{},
+	}
+	seen := map[string]bool{}
+	addNode := func(o *Object) {
+		if k := objectKey(o); !seen[k] {
+			seen[k] = true
+			g.nodes = append(g.nodes, o)
+		}
+	}
+	for _, step := range p.Alternatives {
+		if _, ok := g.forward[step.Predicate]; ok {
+			continue
+		}
+		g.forward[step.Predicate] = map[string][]*Object{}
+		g.backward[step.Predicate] = map[string][]*Object{}
+		facts, err := s.Get(&Query{PredicateFilter: ptrutils.Ptr(step.Predicate)})
+		if err != nil {
+			return nil, fmt.Errorf("store.Get: %v", err)
+		}
+		for _, id := range sortedIDs(facts) {
+			sub, obj := subjectOf(facts[id]), objectOf(facts[id])
+			g.forward[step.Predicate][objectKey(sub)] = append(g.forward[step.Predicate][objectKey(sub)], obj)
+			g.backward[step.Predicate][objectKey(obj)] = append(g.backward[step.Predicate][objectKey(obj)], sub)
+			addNode(sub)
+			addNode(obj)
+		}
+	}
+	return g, nil
+}
+
+// reach returns the nodes connected to the start node by the step, each once.
+// Traversal is breadth first and visits every node at most once per chain
+// length when the length is bounded, and at most once overall when it is
+// not, so cycles in the graph are safe. Bounded traversal stops early once
+// the nodes reached at some length repeat, as no new node can follow.
+func (g *pathGraph) reach(start *Object, step *PathStep) []*Object {
+	edges := g.forward[step.Predicate]
+	if step.Inverse {
+		edges = g.backward[step.Predicate]
+	}
+	expand := func(frontier []*Object, visited map[string]bool) []*Object {
+		next := []*Object{}
+		for _, n := range frontier {
+			for _, m := range edges[objectKey(n)] {
+				if k := objectKey(m); !visited[k] {
+					visited[k] = true
+					next = append(next, m)
+				}
+			}
+		}
+		return next
+	}
+
+	reached := []*Object{}
+	seen := map[string]bool{}
+	add := func(nodes []*Object) {
+		for _, n := range nodes {
+			if k := objectKey(n); !seen[k] {
+				seen[k] = true
+				reached = append(reached, n)
+			}
+		}
+	}
+	// lengths maps the frontiers to the last chain length they were reached
+	// at. A frontier only depends on the previous one, so once a frontier
+	// repeats, the frontiers after it repeat along.
+	lengths := map[string]int{}
+	frontier := []*Object{start}
+	for length := 0; len(frontier) > 0; length++ {
+		k := frontierKey(frontier)
+		if prev, ok := lengths[k]; ok {
+			if prev >= step.Min {
+				// The frontiers of the cycle are all added already.
+				break
+			}
+			// Skip the whole cycles still short of the minimum length.
+			period := length - prev
+			length += (step.Min - length) / period * period
+		}
+		lengths[k] = length
+		if step.Max == nil && length >= step.Min {
+			// Every node reachable from the frontier is connected by a
+			// chain long enough, so compute the closure.
+			visited := map[string]bool{}
+			for _, n := range frontier {
+				visited[objectKey(n)] = true
+			}
+			for next := frontier; len(next) > 0; next = expand(next, visited) {
+				add(next)
+			}
+			break
+		}
+		if length >= step.Min {
+			add(frontier)
+		}
+		if step.Max != nil && length == *step.Max {
+			break
+		}
+		frontier = expand(frontier, map[string]bool{})
+	}
+	return reached
+}
+
+// frontierKey identifies the set of nodes, whatever their order.
+func frontierKey(nodes []*Object) string {
+	keys := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		keys = append(keys, objectKey(n))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\x00")
+}
+
+// joinPath extends every row with the subjects and objects connected by the
+// predicate path of q. Path patterns do not match single facts, so the rows
+// are not extended with facts.
+//
+// Unbound subjects range over the nodes having an edge of the path's
+// predicates, which also limits the zero length chains of * and {0,n}.
+func joinPath(s Reader, rows []*Row, q *Query) ([]*Row, error) {
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	g, err := newPathGraph(s, q.PredicatePath)
+	if err != nil {
+		return nil, err
+	}
+	joined := []*Row{}
+	for _, r := range rows {
+		starts := g.nodes
+		if q.SubjectFilter != nil {
+			starts = []*Object{ObjectFromSubject(*q.SubjectFilter)}
+		} else if o, ok := r.Bindings[*q.SubjectVar]; ok {
+			starts = []*Object{o}
+		}
+		for _, start := range starts {
+			seen := map[string]bool{}
+			for _, step := range q.PredicatePath.Alternatives {
+				for _, end := range g.reach(start, step) {
+					if seen[objectKey(end)] {
+						continue
+					}
+					seen[objectKey(end)] = true
+					b := r.Bindings.Copy()
+					if q.SubjectVar != nil && !b.bind(*q.SubjectVar, start) {
+						continue
+					}
+					if !q.matchObject(end, b) {
+						continue
+					}
+					joined = append(joined, &Row{Bindings: b, FactIDs: r.FactIDs, Facts: r.Facts})
+				}
+			}
+		}
+	}
+	return joined, nil
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ozansz/semantix/internal/parser"
+)
+
+const (
+	returnedVarPrefix = "?"
+)
+
+// Bindings maps query variables, including their sigil, to their values.
+type Bindings map[string]*Object
+
+// Row is a single solution of a query.
+type Row struct {
+	Bindings Bindings
+	// FactIDs and Facts hold the facts matched to produce the row, in the
+	// order of the patterns that matched them.
+	FactIDs []uint32
+	Facts   []*parser.Fact
+}
+
+// Result holds the solutions of a query, projected onto its returned variables.
+type Result struct {
+	Variables []string
+	Rows      []*Row
+}
+
+// IsReturnedVar reports whether the variable is projected into query results
+// (?x) rather than only being used for joins (!x).
+func IsReturnedVar(v string) bool {
+	return strings.HasPrefix(v, returnedVarPrefix)
+}
+
+func (b Bindings) Copy() Bindings {
+	nb := make(Bindings, len(b))
+	for k, v := range b {
+		nb[k] = v
+	}
+	return nb
+}
+
+func (b Bindings) bind(v string, o *Object) bool {
+	if bound, ok := b[v]; ok {
+		return bound.Equal(o)
+	}
+	b[v] = o
+	return true
+}
+
+// key identifies the bindings by their variables and values, for use as a
+// map key.
+func (b Bindings) key() string {
+	vars := make([]string, 0, len(b))
+	for v := range b {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	var sb strings.Builder
+	for _, v := range vars {
+		sb.WriteString(v)
+		sb.WriteRune('=')
+		sb.WriteString(objectKey(b[v]))
+		sb.WriteRune(';')
+	}
+	return sb.String()
+}
+
+// Project returns the bindings of the given variables only.
+func (b Bindings) Project(vars []string) Bindings {
+	nb := make(Bindings, len(vars))
+	for _, v := range vars {
+		if o, ok := b[v]; ok {
+			nb[v] = o
+		}
+	}
+	return nb
+}
+
+func (r *Row) Pretty(vars []string) string {
+	var sb strings.Builder
+	for i, v := range vars {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(v)
+		sb.WriteString(" = ")
+		if o, ok := r.Bindings[v]; ok {
+			sb.WriteString(o.String())
+		} else {
+			sb.WriteString("_")
+		}
+	}
+	return sb.String()
+}
+
+// Evaluate runs the query against the store and returns its solutions,
+// projected onto the query's returned variables, or onto the group and
+// aggregate variables of an aggregating query.
+func Evaluate(s Reader, q *Query) (*Result, error) {
+	rows, err := Solve(s, q)
+	if err != nil {
+		return nil, err
+	}
+	vars := q.Variables()
+	if m := q.Modifiers; m != nil && len(m.Aggregates) > 0 {
+		vars = m.Variables()
+	}
+	return newResult(vars, rows), nil
+}
+
+// Solve runs the query against the store and returns its solutions with all
+// of their bindings. Linked queries are joined on the variables they share
+// with the preceding ones, as left outer joins for optional ones, and the
+// solutions are then post-processed by the query's modifiers. Patterns match
+// the facts of the graphs and the scope selected by the modifiers, the
+// asserted facts of every graph unless stated otherwise.
+//
+// Unless ordered by the modifiers, solutions are ordered by the IDs of the
+// facts matched by the patterns, in pattern order.
+func Solve(s Reader, q *Query) ([]*Row, error) {
+	if m := q.Modifiers; m != nil && len(m.From) > 0 {
+		g, ok := s.(GraphReader)
+		if !ok {
+			return nil, fmt.Errorf("graphs can not be selected in %s", q.Pretty())
+		}
+		s = g.InGraphs(m.From)
+	}
+	if m := q.Modifiers; m != nil && m.Scope != "" && m.Scope != ScopeAsserted {
+		s = &scopedReader{base: s, scope: m.Scope}
+	}
+	rows, err := joinChain(s, []*Row{{Bindings: Bindings{}}}, q)
+	if err != nil {
+		return nil, err
+	}
+	if m := q.Modifiers; m != nil {
+		if len(m.Aggregates) > 0 {
+			if rows, err = m.aggregate(rows); err != nil {
+				return nil, err
+			}
+		}
+		m.order(rows)
+		rows = m.slice(rows)
+	}
+	return rows, nil
+}
+
+// joinChain joins the rows with q and each of its linked queries in turn.
+func joinChain(s Reader, rows []*Row, q *Query) ([]*Row, error) {
+	for curr := q; curr != nil; curr = curr.LinkedQuery {
+		var err error
+		rows, err = join(s, rows, curr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// join extends every row with the facts matching the pattern of q that agree
+// with the row's bindings.
+//
+// Negated terms are evaluated as negation-as-failure: a negated term matches
+// any other value, and the extended row is dropped if the pattern with its
+// negated terms replaced by the terms they negate holds for the row's bindings.
+func join(s Reader, rows []*Row, q *Query) ([]*Row, error) {
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	if q.Optional {
+		return joinOptional(s, rows, q)
+	}
+	if len(q.Union) > 0 {
+		return joinUnion(s, rows, q)
+	}
+	if q.Exists != nil {
+		return filterExists(s, rows, q.Exists, true)
+	}
+	if q.NotExists != nil {
+		return filterExists(s, rows, q.NotExists, false)
+	}
+	if q.Within != nil {
+		return joinWithin(s, rows, q.Within)
+	}
+	if q.Filter != nil {
+		return joinFilter(s, rows, q.Filter)
+	}
+	if q.Binding != nil {
+		return joinBind(s, rows, q.Binding)
+	}
+	if q.PredicatePath != nil {
+		return joinPath(s, rows, q)
+	}
+	facts, err := s.Get(q)
+	if err != nil {
+		return nil, fmt.Errorf("store.Get: %v", err)
+	}
+	var refuting map[uint32]*parser.Fact
+	positive := q.positive()
+	if positive != nil {
+		refuting, err = s.Get(positive)
+		if err != nil {
+			return nil, fmt.Errorf("store.Get: %v", err)
+		}
+	}
+	ids := sortedIDs(facts)
+	joined := []*Row{}
+	for _, r := range rows {
+		for _, id := range ids {
+			f := facts[id]
+			b, ok := q.Bind(f, r.Bindings)
+			if !ok {
+				continue
+			}
+			if positive != nil && anyBinds(positive, refuting, b) {
+				continue
+			}
+			joined = append(joined, r.extend(b, id, f))
+		}
+	}
+	return joined, nil
+}
+
+// joinOptional extends every row as join does, keeping the rows that cannot be
+// extended as they are.
+func joinOptional(s Reader, rows []*Row, q *Query) ([]*Row, error) {
+	required := *q
+	required.Optional = false
+	joined := []*Row{}
+	for _, r := range rows {
+		extended, err := join(s, []*Row{r}, &required)
+		if err != nil {
+			return nil, err
+		}
+		if len(extended) == 0 {
+			extended = []*Row{r}
+		}
+		joined = append(joined, extended...)
+	}
+	return joined, nil
+}
+
+// joinUnion extends every row with the solutions of each of the alternative
+// chains of q. Extensions of a row with the same bindings are only kept once,
+// with the facts of the first alternative producing them.
+func joinUnion(s Reader, rows []*Row, q *Query) ([]*Row, error) {
+	joined := []*Row{}
+	for _, r := range rows {
+		seen := map[string]bool{}
+		for _, u := range q.Union {
+			extended, err := joinChain(s, []*Row{r}, u)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range extended {
+				if k := e.Bindings.key(); !seen[k] {
+					seen[k] = true
+					joined = append(joined, e)
+				}
+			}
+		}
+	}
+	return joined, nil
+}
+
+// filterExists keeps the rows for which the sub-query has solutions, or has
+// none if not exists. The sub-query sees the row's bindings, but its own
+// variables are not bound in the kept rows.
+func filterExists(s Reader, rows []*Row, sub *Query, exists bool) ([]*Row, error) {
+	kept := []*Row{}
+	for _, r := range rows {
+		solutions, err := joinChain(s, []*Row{r}, sub)
+		if err != nil {
+			return nil, err
+		}
+		if (len(solutions) > 0) == exists {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// anyBinds reports whether any of the facts matches the pattern of q
+// consistently with the given bindings.
+func anyBinds(q *Query, facts map[uint32]*parser.Fact, b Bindings) bool {
+	for _, f := range facts {
+		if _, ok := q.Bind(f, b); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// extend returns a new row with the given bindings and the matched fact
+// appended to the row's facts.
+func (r *Row) extend(b Bindings, id uint32, f *parser.Fact) *Row {
+	nr := &Row{
+		Bindings: b,
+		FactIDs:  make([]uint32, len(r.FactIDs), len(r.FactIDs)+1),
+		Facts:    make([]*parser.Fact, len(r.Facts), len(r.Facts)+1),
+	}
+	copy(nr.FactIDs, r.FactIDs)
+	copy(nr.Facts, r.Facts)
+	nr.FactIDs = append(nr.FactIDs, id)
+	nr.Facts = append(nr.Facts, f)
+	return nr
+}
+
+func newResult(vars []string, rows []*Row) *Result {
+	for _, r := range rows {
+		r.Bindings = r.Bindings.Project(vars)
+	}
+	return &Result{
+		Variables: vars,
+		Rows:      rows,
+	}
+}
+
+func sortedIDs(facts map[uint32]*parser.Fact) []uint32 {
+	ids := make([]uint32, 0, len(facts))
+	for id := range facts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
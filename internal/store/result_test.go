@@ -0,0 +1,713 @@
+package store_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+	"github.com/ozansz/semantix/internal/store/filestore"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		desc      string
+		facts     []string
+		query     string
+		wantVars  []string
+		want      []string
+		ordered   bool
+		wantError bool
+	}{
+		{
+			desc:     "subject variable",
+			query:    "(?x, is, Person)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "subject and object variables",
+			query:    "(?x, knows, ?y)",
+			wantVars: []string{"?x", "?y"},
+			want: []string{
+				"?x = Ozan, ?y = CS",
+				"?x = Ufuk, ?y = (Ozan, knows, CS)",
+				"?x = Ufuk, ?y = CS",
+			},
+		},
+		{
+			desc:     "nested pattern variables",
+			query:    "(?x, knows, (?y, knows, CS))",
+			wantVars: []string{"?x", "?y"},
+			want:     []string{"?x = Ufuk, ?y = Ozan"},
+		},
+		{
+			desc:     "hidden variables are not projected",
+			query:    "(?x, knows, (!y, knows, CS))",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ufuk"},
+		},
+		{
+			desc:     "repeated variables enforce equality",
+			facts:    []string{"(Ozan, likes, Ozan)", "(Ozan, likes, Ufuk)"},
+			query:    "(?x, likes, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "predicate variable",
+			query:    "(Ozan, ?p, CS)",
+			wantVars: []string{"?p"},
+			want:     []string{"?p = knows"},
+		},
+		{
+			desc:     "linked query",
+			query:    "(?x, knows, !y) -> (!y, subtopicOf, Science)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "linked compound query",
+			query:    "(!x, is, Person) -> (?y, is, Person) -> (?y, knows, (!x, knows, CS))",
+			wantVars: []string{"?y"},
+			want:     []string{"?y = Ufuk"},
+		},
+		{
+			desc:     "linked query without solutions",
+			query:    "(?x, knows, !y) -> (!y, subtopicOf, Math)",
+			wantVars: []string{"?x"},
+			want:     []string{},
+		},
+		{
+			desc:     "optional pattern",
+			facts:    []string{"(Ozan, email, \"ozan@metu.edu.tr\")"},
+			query:    "(?x, is, Person) -> optional (?x, email, ?e)",
+			wantVars: []string{"?x", "?e"},
+			want:     []string{"?x = Ozan, ?e = \"ozan@metu.edu.tr\"", "?x = Ufuk, ?e = _"},
+		},
+		{
+			desc: "optional chain",
+			facts: []string{
+				"(Ozan, email, ozanMail)",
+				"(Ufuk, email, ufukMail)",
+				"(ozanMail, domain, metu)",
+			},
+			query:    "(?x, is, Person) -> optional (?x, email, !e) -> optional (!e, domain, ?d)",
+			wantVars: []string{"?x", "?d"},
+			want:     []string{"?x = Ozan, ?d = metu", "?x = Ufuk, ?d = _"},
+		},
+		{
+			desc:     "required pattern after optional",
+			query:    "(?x, is, Person) -> optional (?x, age, ?a) -> (?x, knows, CS)",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ozan, ?a = 24", "?x = Ufuk, ?a = _"},
+		},
+		{
+			desc:     "union",
+			facts:    []string{"(Ezgi, knows, Math)"},
+			query:    "{ (?x, knows, CS) | (?x, knows, Math) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ezgi", "?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "union with duplicate bindings",
+			query:    "{ (?x, is, Person) | (?x, knows, CS) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "union of chains joined downstream",
+			facts:    []string{"(Ezgi, likes, Science)", "(Ezgi, age, 25)"},
+			query:    "{ (?x, knows, !t) -> (!t, subtopicOf, Science) | (?x, likes, Science) } -> (?x, age, ?a)",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ezgi, ?a = 25", "?x = Ozan, ?a = 24"},
+		},
+		{
+			desc:      "union as nested fact",
+			query:     "(?x, knows, { (Ozan, knows, CS) | (Ufuk, knows, CS) })",
+			wantError: true,
+		},
+		{
+			desc:     "not exists",
+			facts:    []string{"(Ozan, approvedBy, METU)"},
+			query:    "(?x, is, Person) -> not exists { (?x, approvedBy, !y) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ufuk"},
+		},
+		{
+			desc:     "exists with a chain",
+			facts:    []string{"(Ezgi, is, Person)", "(Ezgi, knows, Art)"},
+			query:    "(?x, is, Person) -> exists { (?x, knows, !t) -> (!t, subtopicOf, Science) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "exists does not bind its variables",
+			query:    "(?x, is, Person) -> exists { (?x, age, ?a) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:      "optional exists",
+			query:     "(?x, is, Person) -> optional exists { (?x, age, ?a) }",
+			wantError: true,
+		},
+		{
+			desc:     "bind arithmetic",
+			query:    "(?x, age, ?a) -> bind ?a * 12 as ?m",
+			wantVars: []string{"?x", "?a", "?m"},
+			want:     []string{"?x = Ozan, ?a = 24, ?m = 288"},
+		},
+		{
+			desc:     "operator precedence",
+			query:    "(Ozan, age, ?a) -> bind ?a + 6 / 2 * 3 - -1 as ?v -> bind (?a + 6) % 7 as ?w",
+			wantVars: []string{"?a", "?v", "?w"},
+			want:     []string{"?a = 24, ?v = 34.0, ?w = 2"},
+		},
+		{
+			desc:     "bind string functions",
+			query:    "(?x, name, ?n) -> bind concat(upper(substr(?n, 1, 4)), \" is \", str(?x)) as ?s",
+			wantVars: []string{"?x", "?n", "?s"},
+			want:     []string{"?x = Ozan, ?n = \"Ozan Sazak!!!\", ?s = \"OZAN is Ozan\""},
+		},
+		{
+			desc:     "bind comparison",
+			query:    "(?x, is, Person) -> optional (?x, age, ?a) -> bind ?a >= 18 as ?adult",
+			wantVars: []string{"?x", "?a", "?adult"},
+			want:     []string{"?x = Ozan, ?a = 24, ?adult = true", "?x = Ufuk, ?a = _, ?adult = _"},
+		},
+		{
+			desc:     "filter",
+			facts:    []string{"(Ezgi, age, 17)"},
+			query:    "(?x, age, ?a) -> filter ?a >= 18 and ?a < 30",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ozan, ?a = 24"},
+		},
+		{
+			desc:     "dates compare chronologically",
+			facts:    []string{"(Ozan, born, 1999-05-04)", "(Ufuk, born, 2001-11-30)", "(Ezgi, born, \"1998-01-01\")"},
+			query:    "(?x, born, < 2000-01-01)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "timestamps match the same instant",
+			facts:    []string{"(Ozan, joined, 2023-07-08T14:30:00+03:00)", "(Ufuk, joined, 2023-07-08T14:30:00Z)"},
+			query:    "(?x, joined, 2023-07-08T11:30:00Z)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "booleans",
+			facts:    []string{"(Ozan, active, true)", "(Ufuk, active, false)"},
+			query:    "(?x, active, ?v) -> filter ?v",
+			wantVars: []string{"?x", "?v"},
+			want:     []string{"?x = Ozan, ?v = true"},
+		},
+		{
+			desc:     "integers compare numerically with floats",
+			facts:    []string{"(Ezgi, age, 24.5)"},
+			query:    "(?x, age, ?a) -> filter ?a = 24.0 or ?a > 24",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ezgi, ?a = 24.5", "?x = Ozan, ?a = 24"},
+		},
+		{
+			desc:     "integer arithmetic",
+			query:    "(Ozan, age, ?a) -> bind ?a / 5 as ?q -> bind ?a % 5 as ?r -> bind ?a * 2.5 as ?f",
+			wantVars: []string{"?a", "?q", "?r", "?f"},
+			want:     []string{"?a = 24, ?q = 4.8, ?r = 4, ?f = 60.0"},
+		},
+		{
+			desc:     "language filter",
+			facts:    []string{`(Ozan, label, "Ozan"@en)`, `(Ozan, label, "Ozan"@tr)`, `(Ufuk, label, "Ufuk"@en-gb)`, `(Ezgi, label, "Ezgi")`},
+			query:    "(?x, label, @en)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "language-tagged strings are distinct",
+			facts:    []string{`(Ozan, label, "Ozan"@en)`, `(Ozan, label, "Ozan"@tr)`},
+			query:    `(Ozan, label, ?l) -> filter ?l != "Ozan"@en and langmatches(lang(?l), "TR")`,
+			wantVars: []string{"?l"},
+			want:     []string{`?l = "Ozan"@tr`},
+		},
+		{
+			desc:     "datatyped strings",
+			facts:    []string{`(Ozan, code, "42"^^<http://example.org/types#code>)`, `(Ufuk, code, "42")`},
+			query:    `(?x, code, "42"^^<http://example.org/types#code>) -> (?x, code, ?c) -> bind datatype(?c) as ?t`,
+			wantVars: []string{"?x", "?c", "?t"},
+			want:     []string{`?x = Ozan, ?c = "42"^^<http://example.org/types#code>, ?t = <http://example.org/types#code>`},
+		},
+		{
+			desc:     "filter unbound variables",
+			query:    "(?x, is, Person) -> optional (?x, age, ?a) -> filter ?a < 30",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ozan, ?a = 24"},
+		},
+		{
+			desc:     "filter bound",
+			query:    "(?x, is, Person) -> optional (?x, age, ?a) -> filter not bound(?a) or ?a > 30",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ufuk, ?a = _"},
+		},
+		{
+			desc:     "filter strings",
+			query:    "(?x, name, ?n) -> filter strlen(?n) > 10 and contains(?n, \"Sazak\") and ?x = Ozan",
+			wantVars: []string{"?x", "?n"},
+			want:     []string{"?x = Ozan, ?n = \"Ozan Sazak!!!\""},
+		},
+		{
+			desc:     "bind joined downstream",
+			facts:    []string{"(Ezgi, age, 25)", "(Ezgi, knows, Ozan)"},
+			query:    "(?x, age, ?a) -> bind ?a - 1 as ?b -> (?y, age, ?b) -> (?x, knows, ?y)",
+			wantVars: []string{"?x", "?a", "?b", "?y"},
+			want:     []string{"?x = Ezgi, ?a = 25, ?b = 24, ?y = Ozan"},
+		},
+		{
+			desc:      "arithmetic on strings",
+			query:     "(?x, name, ?n) -> bind ?n + 1 as ?m",
+			wantError: true,
+		},
+		{
+			desc:     "quoted facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(?x, is, Person) match quoted",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ali", "?x = Ozan"},
+		},
+		{
+			desc:     "asserted and quoted facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(?x, is, Person) match any",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ali", "?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "asserted facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(?x, is, Person) match asserted",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "quoted facts at any depth",
+			query:    "(?x, knows, ?y) match quoted",
+			wantVars: []string{"?x", "?y"},
+			want:     []string{"?x = Ozan, ?y = CS", "?x = Ufuk, ?y = (Ozan, knows, CS)"},
+		},
+		{
+			desc:     "asserted nested facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(Ezgi, thinks, ?f) -> filter asserted(?f)",
+			wantVars: []string{"?f"},
+			want:     []string{"?f = (Ozan, is, Person)"},
+		},
+		{
+			desc:     "only quoted nested facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(Ezgi, thinks, ?f) -> filter not asserted(?f)",
+			wantVars: []string{"?f"},
+			want:     []string{"?f = (Ali, is, Person)"},
+		},
+		{
+			desc:     "from the default graph",
+			query:    "(?x, is, Person) from default",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "from other graphs",
+			query:    "(?x, is, Person) from HR, CRM",
+			wantVars: []string{"?x"},
+			want:     []string{},
+		},
+		{
+			desc:     "within an agent's context",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "within (Ezgi, thinks) { (?x, is, Person) }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ali", "?x = Ozan"},
+		},
+		{
+			desc:     "within the contexts of every agent",
+			facts:    []string{"(Ali, thinks, (Veli, is, Person))", "(Ali, knows, (Ayse, is, Person))"},
+			query:    "within (?a, thinks) { (?x, is, Person) }",
+			wantVars: []string{"?a", "?x"},
+			want:     []string{"?a = Ali, ?x = Veli", "?a = Ezgi, ?x = Ozan"},
+		},
+		{
+			desc:     "within joined with asserted facts",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))", "(Ozan, thinks, (Ufuk, is, Person))"},
+			query:    "(?a, is, Person) -> within (?a, thinks) { (?x, is, Person) }",
+			wantVars: []string{"?a", "?x"},
+			want:     []string{"?a = Ozan, ?x = Ufuk"},
+		},
+		{
+			desc:     "within nested contexts",
+			query:    "within (Ezgi, notKnows) { within (Ufuk, knows) { (?x, knows, ?y) } }",
+			wantVars: []string{"?x", "?y"},
+			want:     []string{"?x = Ozan, ?y = CS"},
+		},
+		{
+			desc:     "not within",
+			facts:    []string{"(Ezgi, thinks, (Ali, is, Person))"},
+			query:    "(?x, is, Person) -> not exists { within (Ezgi, thinks) { (?x, is, Person) } }",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ufuk"},
+		},
+		{
+			desc:      "asserted on a value",
+			query:     "(?x, age, ?a) -> filter asserted(?a)",
+			wantError: true,
+		},
+		{
+			desc:      "filter on a number",
+			query:     "(?x, age, ?a) -> filter ?a + 1",
+			wantError: true,
+		},
+		{
+			desc:      "integer overflow",
+			query:     "(?x, age, ?a) -> bind 9223372036854775807 + ?a as ?b",
+			wantError: true,
+		},
+		{
+			desc:      "integer overflow in abs",
+			query:     "(?x, age, ?a) -> bind abs(-9223372036854775807 - (?a - 23)) as ?b",
+			wantError: true,
+		},
+		{
+			desc:      "integer overflow in a sum",
+			facts:     []string{"(Big, age, 9223372036854775807)"},
+			query:     "(?x, age, ?a) aggregate sum(?a) as ?sum",
+			wantError: true,
+		},
+		{
+			desc:      "unknown function",
+			query:     "(?x, age, ?a) -> bind age(?x) as ?m",
+			wantError: true,
+		},
+		{
+			desc:     "float constant matching an integer",
+			query:    "(?x, age, 24.0)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "integer constant matching a float",
+			facts:    []string{"(Ezgi, age, 25.0)", "(Ufuk, age, 25.5)"},
+			query:    "(?x, age, 25)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ezgi"},
+		},
+		{
+			desc:     "equality comparison",
+			facts:    []string{"(Ezgi, age, 24.0)", "(Ufuk, age, 24.5)"},
+			query:    "(?x, age, = 24.0)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ezgi", "?x = Ozan"},
+		},
+		{
+			desc:     "negated number matching an integer",
+			facts:    []string{"(Ezgi, age, 25.0)"},
+			query:    "(?x, age, ~24.0)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ezgi"},
+		},
+		{
+			desc:     "negated object",
+			facts:    []string{"(Ozan, is, Engineer)", "(METU, is, University)"},
+			query:    "(?x, is, ~Person)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = METU"},
+		},
+		{
+			desc:     "negated predicate",
+			facts:    []string{"(Ozan, teaches, CS)", "(Ezgi, teaches, CS)"},
+			query:    "(?x, ~knows, CS)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ezgi"},
+		},
+		{
+			desc:     "negated subject",
+			facts:    []string{"(METU, is, University)"},
+			query:    "(~Ozan, is, ?y)",
+			wantVars: []string{"?y"},
+			want:     []string{"?y = University"},
+		},
+		{
+			desc:     "negated nested pattern term",
+			facts:    []string{"(Ezgi, thinks, (Ufuk, is, Robot))"},
+			query:    "(?x, thinks, (?y, is, ~Person))",
+			wantVars: []string{"?x", "?y"},
+			want:     []string{"?x = Ezgi, ?y = Ufuk"},
+		},
+		{
+			desc:     "numeric comparison",
+			facts:    []string{"(Ufuk, age, 20)", "(Ezgi, age, 19)"},
+			query:    "(?x, age, >= 20)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "numeric range",
+			facts:    []string{"(Ozan, length, 200)", "(Ezgi, length, 99.5)"},
+			query:    "(?x, length, 100..200)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "string prefix",
+			facts:    []string{`(Ufuk, name, "Ufuk")`},
+			query:    `(?x, name, ^= "Oz")`,
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "string contains",
+			facts:    []string{`(Ufuk, name, "Ufuk")`},
+			query:    `(?x, name, *= "fu")`,
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ufuk"},
+		},
+		{
+			desc:     "string regex",
+			facts:    []string{`(Ufuk, name, "Ufuk")`},
+			query:    `(?x, name, =~ "(?i)^oz.*!$")`,
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan"},
+		},
+		{
+			desc:     "not equal",
+			facts:    []string{"(METU, is, University)"},
+			query:    "(?x, is, != Person)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = METU"},
+		},
+		{
+			desc:     "count",
+			query:    "(?x, is, Person) aggregate count(?x) as ?n",
+			wantVars: []string{"?n"},
+			want:     []string{"?n = 2"},
+		},
+		{
+			desc:     "count without solutions",
+			query:    "(?x, is, Robot) aggregate count(*) as ?n, max(?x) as ?max",
+			wantVars: []string{"?n", "?max"},
+			want:     []string{"?n = 0, ?max = _"},
+		},
+		{
+			desc: "grouped aggregates",
+			facts: []string{
+				"(Ozan, team, Core)", "(Ufuk, team, Core)", "(Ezgi, team, Web)",
+				"(Ufuk, age, 30)", "(Ezgi, age, 27)",
+			},
+			query:    "(!p, team, ?t) -> (!p, age, !a) aggregate avg(!a) as ?avg, sum(!a) as ?sum, min(!a) as ?min, count(!p) as ?n group by ?t",
+			wantVars: []string{"?t", "?avg", "?sum", "?min", "?n"},
+			want: []string{
+				"?t = Core, ?avg = 27.0, ?sum = 54, ?min = 24, ?n = 2",
+				"?t = Web, ?avg = 27.0, ?sum = 27, ?min = 27, ?n = 1",
+			},
+		},
+		{
+			desc:      "sum of non-numeric values",
+			query:     "(?x, is, ?y) aggregate sum(?y) as ?sum",
+			wantError: true,
+		},
+		{
+			desc: "order by number descending",
+			facts: []string{
+				"(Ufuk, age, 30)", "(Ezgi, age, 27)", "(Ahmet, age, 3)",
+			},
+			query:    "(?x, age, ?a) order by ?a desc",
+			wantVars: []string{"?x", "?a"},
+			want: []string{
+				"?x = Ufuk, ?a = 30",
+				"?x = Ezgi, ?a = 27",
+				"?x = Ozan, ?a = 24",
+				"?x = Ahmet, ?a = 3",
+			},
+			ordered: true,
+		},
+		{
+			desc:     "order by subject lexically",
+			facts:    []string{"(Ahmet, is, Person)"},
+			query:    "(?x, is, Person) order by ?x",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ahmet", "?x = Ozan", "?x = Ufuk"},
+			ordered:  true,
+		},
+		{
+			desc:     "limit and offset",
+			facts:    []string{"(Ahmet, is, Person)", "(Ezgi, is, Person)"},
+			query:    "(?x, is, Person) order by ?x desc limit 2 offset 1",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ezgi"},
+			ordered:  true,
+		},
+		{
+			desc:     "offset past the last solution",
+			query:    "(?x, is, Person) offset 5",
+			wantVars: []string{"?x"},
+			want:     []string{},
+		},
+		{
+			desc: "order aggregated solutions",
+			facts: []string{
+				"(Ozan, team, Core)", "(Ufuk, team, Core)", "(Ezgi, team, Web)",
+			},
+			query:    "(!p, team, ?t) aggregate count(!p) as ?n group by ?t order by ?n",
+			wantVars: []string{"?t", "?n"},
+			want:     []string{"?t = Web, ?n = 1", "?t = Core, ?n = 2"},
+			ordered:  true,
+		},
+		{
+			desc:     "transitive path",
+			facts:    topicFacts,
+			query:    "(CS, subtopicOf+, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Knowledge", "?x = STEM", "?x = Science"},
+		},
+		{
+			desc:     "reflexive transitive path",
+			facts:    topicFacts,
+			query:    "(?x, subtopicOf*, Science) order by ?x",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = AI", "?x = CS", "?x = Knowledge", "?x = STEM", "?x = Science"},
+			ordered:  true,
+		},
+		{
+			desc:     "bounded path",
+			facts:    topicFacts,
+			query:    "(AI, subtopicOf{2,3}, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Knowledge", "?x = STEM", "?x = Science"},
+		},
+		{
+			desc:     "exact path length on a cycle",
+			facts:    topicFacts,
+			query:    "(Knowledge, subtopicOf{3}, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Knowledge"},
+		},
+		{
+			desc:     "large upper bound on a cycle",
+			facts:    topicFacts,
+			query:    "(AI, subtopicOf{1,100000000}, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = CS", "?x = Knowledge", "?x = STEM", "?x = Science"},
+		},
+		{
+			desc:     "large exact length on a cycle",
+			facts:    topicFacts,
+			query:    "(Knowledge, subtopicOf{99999998}, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Science"},
+		},
+		{
+			desc:     "path alternatives",
+			facts:    []string{"(Ozan, likes, Pizza)"},
+			query:    "(Ozan, knows|likes, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = CS", "?x = Pizza"},
+		},
+		{
+			desc:     "inverse path",
+			query:    "(CS, ^knows, ?x)",
+			wantVars: []string{"?x"},
+			want:     []string{"?x = Ozan", "?x = Ufuk"},
+		},
+		{
+			desc:     "path joined with patterns",
+			facts:    topicFacts,
+			query:    "(?x, knows, !t) -> (!t, subtopicOf+, Science) -> (?x, age, ?a)",
+			wantVars: []string{"?x", "?a"},
+			want:     []string{"?x = Ozan, ?a = 24"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			s := newStore(t, p, tc.facts)
+			exp, err := p.ParseLine(tc.query)
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+			q, err := store.QueryFromAST(exp.Query)
+			if err == nil {
+				q.Modifiers, err = store.ModifiersFromAST(exp.Modifiers)
+			}
+			if err != nil {
+				if tc.wantError {
+					return
+				}
+				t.Fatalf("failed to convert query: %v", err)
+			}
+			res, err := store.Evaluate(s, q)
+			if tc.wantError {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to evaluate query: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantVars, res.Variables); diff != "" {
+				t.Errorf("unexpected variables (-want +got):\n%s", diff)
+			}
+			got := []string{}
+			for _, r := range res.Rows {
+				got = append(got, r.Pretty(res.Variables))
+			}
+			if !tc.ordered {
+				sort.Strings(got)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected rows (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// topicFacts form a cycle of subtopics: AI -> CS -> STEM -> Science ->
+// Knowledge -> STEM, next to the CS -> Science shortcut of the examples.
+var topicFacts = []string{
+	"(AI, subtopicOf, CS)",
+	"(CS, subtopicOf, STEM)",
+	"(STEM, subtopicOf, Science)",
+	"(Science, subtopicOf, Knowledge)",
+	"(Knowledge, subtopicOf, STEM)",
+}
+
+// newStore returns an in-memory store holding the example facts and the given
+// additional ones.
+func newStore(t *testing.T, p *parser.Parser, facts []string) store.Store {
+	t.Helper()
+	s, err := filestore.New()
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	for _, file := range []string{"../../examples/v0/0x01-facts.sxql", "../../examples/v0/0x03-topics.sxql"} {
+		f, err := p.ParseFile(file)
+		if err != nil {
+			t.Fatalf("failed to parse facts file: %v", err)
+		}
+		for _, e := range f.Expressions {
+			if err := s.Add(e.Fact); err != nil {
+				t.Fatalf("failed to add fact: %v", err)
+			}
+		}
+	}
+	for _, fact := range facts {
+		e, err := p.ParseLine(fact)
+		if err != nil {
+			t.Fatalf("failed to parse fact %q: %v", fact, err)
+		}
+		if err := s.Add(e.Fact); err != nil {
+			t.Fatalf("failed to add fact: %v", err)
+		}
+	}
+	return s
+}
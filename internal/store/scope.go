@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/internal/parser"
+)
+
+// Scope selects the facts the patterns of a query match. Facts quoted in
+// other facts, at any depth, are not asserted by them: they only hold if they
+// are asserted on their own as well.
+type Scope string
+
+const (
+	ScopeAsserted Scope = "asserted"
+	ScopeQuoted   Scope = "quoted"
+	ScopeAny      Scope = "any"
+)
+
+// scopedReader reads the facts of the scope out of the asserted facts of
+// base. Quoted facts are identified by their hash, like asserted ones, so a
+// fact both asserted and quoted has a single ID.
+type scopedReader struct {
+	base  Reader
+	scope Scope
+}
+
+func (r *scopedReader) Get(q *Query) (map[uint32]*parser.Fact, error) {
+	trs := map[uint32]*parser.Fact{}
+	if r.scope == ScopeAny {
+		asserted, err := r.base.Get(q)
+		if err != nil {
+			return nil, err
+		}
+		for id, t := range asserted {
+			trs[id] = t
+		}
+	}
+	all, err := r.base.Get(&Query{})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		walkQuoted(t, func(n *parser.Fact) {
+			if q.Matches(n) {
+				trs[FactHash(n)] = n.Copy()
+			}
+		})
+	}
+	return trs, nil
+}
+
+// walkQuoted calls fn for every fact nested in the fact, at any depth.
+func walkQuoted(t *parser.Fact, fn func(*parser.Fact)) {
+	for _, n := range []*parser.Fact{t.SubjectFact, t.ObjectFact} {
+		if n != nil {
+			fn(n)
+			walkQuoted(n, fn)
+		}
+	}
+}
+
+// isAsserted reports whether the fact is asserted in the store, whatever the
+// scope the store is read in.
+func isAsserted(s Reader, t *parser.Fact) (bool, error) {
+	if r, ok := s.(*scopedReader); ok {
+		s = r.base
+	}
+	q, err := QueryFromAST(parser.QueryFromFact(t))
+	if err != nil {
+		return false, fmt.Errorf("QueryFromAST(%s): %v", t.Pretty(), err)
+	}
+	facts, err := s.Get(q)
+	if err != nil {
+		return false, err
+	}
+	_, ok := facts[FactHash(t)]
+	return ok, nil
+}
@@ -16,7 +16,57 @@ func (db *DB) Add(t *parser.Fact) error {
 	return nil
 }
 
+// TODO: Implement this
+func (db *DB) AddAll(ts []*parser.Fact) error {
+	return nil
+}
+
 // TODO: Implement this
 func (db *DB) Get(q *store.Query) (map[uint32]*parser.Fact, error) {
 	return nil, nil
 }
+
+// TODO: Implement this
+func (db *DB) GetByID(id uint32) (*parser.Fact, error) {
+	return nil, nil
+}
+
+// TODO: Implement this
+func (db *DB) Delete(t *parser.Fact) error {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) Replace(old, new *parser.Fact) error {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) AddToGraph(graph string, ts []*parser.Fact) error {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) DropGraph(graph string) error {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) ReloadGraph(graph string, ts []*parser.Fact) error {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) Asserted() store.Reader {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) InGraphs(graphs []string) store.Reader {
+	return nil
+}
+
+// TODO: Implement this
+func (db *DB) AddRule(r *store.Rule) error {
+	return nil
+}
@@ -1,6 +1,6 @@
 package fact
 
-// NOTE: The second byte is reserved for future use
+// NOTE: The second byte holds the literal type of the object
 type RowMeta [2]byte
 
 func (r *RowMeta) Active() bool {
@@ -19,6 +19,10 @@ func (r *RowMeta) ObjectType() RowObjectType {
 	return RowObjectType(r[0] & 0b00000111)
 }
 
+func (r *RowMeta) LiteralType() RowLiteralType {
+	return RowLiteralType(r[1])
+}
+
 func (r *RowMeta) SetActive(active bool) {
 	if active {
 		r[0] |= 0b10000000
@@ -42,6 +46,10 @@ func (r *RowMeta) SetObjectType(t RowObjectType) {
 	r[0] |= byte(t)
 }
 
+func (r *RowMeta) SetLiteralType(t RowLiteralType) {
+	r[1] = byte(t)
+}
+
 func NewRowMeta(active bool, t RowType, st RowSubjectType, ot RowObjectType) RowMeta {
 	r := RowMeta{}
 	r.SetActive(active)
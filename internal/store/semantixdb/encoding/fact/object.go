@@ -3,6 +3,7 @@ package fact
 import (
 	"encoding/binary"
 	"math"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/ozansz/semantix/pkg/byteutils"
@@ -28,6 +29,23 @@ func (o Object) UInt64() uint64 {
 	return binary.BigEndian.Uint64(o[:8])
 }
 
+func (o Object) Int64() int64 {
+	return int64(o.UInt64())
+}
+
+func (o Object) Bool() bool {
+	return o.UInt64() != 0
+}
+
+// Time returns the instant held in the first 8 bytes as Unix seconds and in
+// the next 4 bytes as nanoseconds, in the zone whose offset in seconds is held
+// in the 4 bytes after them.
+func (o Object) Time() time.Time {
+	nsec := int64(binary.BigEndian.Uint32(o[8:12]))
+	offset := int(int32(binary.BigEndian.Uint32(o[12:16])))
+	return time.Unix(o.Int64(), nsec).In(time.FixedZone("", offset))
+}
+
 func (o Object) Float64() float64 {
 	return math.Float64frombits(binary.BigEndian.Uint64(o[:8]))
 }
@@ -69,6 +87,27 @@ func NewObjectFromUInt64(val uint64) Object {
 	return o
 }
 
+func NewObjectFromInt64(val int64) Object {
+	return NewObjectFromUInt64(uint64(val))
+}
+
+func NewObjectFromBool(val bool) Object {
+	if val {
+		return NewObjectFromUInt64(1)
+	}
+	return NewObjectFromUInt64(0)
+}
+
+// NewObjectFromTime holds the seconds and the nanoseconds of the instant
+// apart, as Unix nanoseconds only cover the years 1678 to 2262.
+func NewObjectFromTime(val time.Time) Object {
+	o := NewObjectFromInt64(val.Unix())
+	binary.BigEndian.PutUint32(o[8:12], uint32(val.Nanosecond()))
+	_, offset := val.Zone()
+	binary.BigEndian.PutUint32(o[12:16], uint32(int32(offset)))
+	return o
+}
+
 func NewObjectFromFloat64(val float64) Object {
 	var o Object
 	binary.BigEndian.PutUint64(o[:8], math.Float64bits(val))
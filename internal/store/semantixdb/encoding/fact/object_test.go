@@ -0,0 +1,60 @@
+package fact
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectTime(t *testing.T) {
+	tests := []struct {
+		desc string
+		time time.Time
+	}{
+		{
+			desc: "epoch",
+			time: time.Unix(0, 0).UTC(),
+		},
+		{
+			desc: "zone offset and nanoseconds",
+			time: time.Date(2023, 7, 8, 14, 30, 0, 999999999, time.FixedZone("", 3*60*60)),
+		},
+		{
+			desc: "before 1678",
+			time: time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "just before the Unix nanoseconds range",
+			time: time.Date(1677, 9, 21, 0, 12, 43, 145224191, time.UTC),
+		},
+		{
+			desc: "just after the Unix nanoseconds range",
+			time: time.Date(2262, 4, 11, 23, 47, 16, 854775808, time.UTC),
+		},
+		{
+			desc: "after 2262",
+			time: time.Date(2300, 1, 1, 0, 0, 0, 0, time.FixedZone("", -5*60*60)),
+		},
+		{
+			desc: "first year",
+			time: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "last year",
+			time: time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			got := NewObjectFromTime(tc.time).Time()
+			if !got.Equal(tc.time) {
+				t.Fatalf("Object holds wrong time: %v, expected: %v", got, tc.time)
+			}
+			_, gotOffset := got.Zone()
+			_, wantOffset := tc.time.Zone()
+			if gotOffset != wantOffset {
+				t.Fatalf("Object holds wrong zone offset: %d, expected: %d", gotOffset, wantOffset)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/oklog/ulid/v2"
@@ -63,6 +64,12 @@ func (r *Row) Diff(other *Row) string {
 		sb.WriteString("\n- ObjectType: ")
 		sb.WriteString(fmt.Sprintf("%v\n", other.Meta.ObjectType()))
 	}
+	if r.Meta.LiteralType() != other.Meta.LiteralType() {
+		sb.WriteString("+ LiteralType: ")
+		sb.WriteString(fmt.Sprintf("%v", r.Meta.LiteralType()))
+		sb.WriteString("\n- LiteralType: ")
+		sb.WriteString(fmt.Sprintf("%v\n", other.Meta.LiteralType()))
+	}
 	switch r.Meta.SubjectType() {
 	case RowSubjectTypeMinString:
 		if r.Subject.MinString() != other.Subject.MinString() {
@@ -132,9 +139,9 @@ func (r *Row) Diff(other *Row) string {
 	case RowObjectTypeFloat64:
 		if r.Object.Float64() != other.Object.Float64() {
 			sb.WriteString("+ Object: ")
-			sb.WriteString(fmt.Sprintf("%f", r.Object.Float64()))
+			sb.WriteString(strconv.FormatFloat(r.Object.Float64(), 'g', -1, 64))
 			sb.WriteString("\n- Object: ")
-			sb.WriteString(fmt.Sprintf("%f\n", other.Object.Float64()))
+			sb.WriteString(strconv.FormatFloat(other.Object.Float64(), 'g', -1, 64) + "\n")
 		}
 	case RowObjectTypeSubjectMinString:
 		if r.Object.SubjectMinString() != other.Object.SubjectMinString() {
@@ -3,6 +3,7 @@ package fact
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 )
@@ -54,6 +55,18 @@ func TestEncodeDecodeRow(t *testing.T) {
 			beforeEncodeCustomChecks: checkForJohnHeight183,
 			afterDecodeCustomChecks:  checkForJohnHeight183,
 		},
+		{
+			desc: "basic row, min string and timestamp",
+			row: &Row{
+				ID:        useULID(),
+				Meta:      timestampRowMeta(),
+				Subject:   NewSubjectFromMinString("Ozan"),
+				Predicate: PredicateFromMinString("joined"),
+				Object:    NewObjectFromTime(ozanJoined()),
+			},
+			beforeEncodeCustomChecks: checkForOzanJoined,
+			afterDecodeCustomChecks:  checkForOzanJoined,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -178,3 +191,29 @@ func checkForJohnHeight183(t *testing.T, row *Row) {
 		t.Fatalf("Row has wrong object: %f, expected: %f", row.Object.Float64(), 1.83)
 	}
 }
+
+func timestampRowMeta() RowMeta {
+	m := NewRowMeta(true, RowTypeBasic, RowSubjectTypeMinString, RowObjectTypeUInt64)
+	m.SetLiteralType(RowLiteralTypeTimestamp)
+	return m
+}
+
+func ozanJoined() time.Time {
+	return time.Date(2023, 7, 8, 14, 30, 0, 500, time.FixedZone("", 3*60*60))
+}
+
+func checkForOzanJoined(t *testing.T, row *Row) {
+	if row.Meta.ObjectType() != RowObjectTypeUInt64 {
+		t.Fatalf("Row is not marked as uint64 object, got %d", row.Meta.ObjectType())
+	}
+	if row.Meta.LiteralType() != RowLiteralTypeTimestamp {
+		t.Fatalf("Row is not marked as timestamp literal, got %d", row.Meta.LiteralType())
+	}
+	got := row.Object.Time()
+	if !got.Equal(ozanJoined()) {
+		t.Fatalf("Row has wrong object: %v, expected: %v", got, ozanJoined())
+	}
+	if _, offset := got.Zone(); offset != 3*60*60 {
+		t.Fatalf("Row has wrong zone offset: %d, expected: %d", offset, 3*60*60)
+	}
+}
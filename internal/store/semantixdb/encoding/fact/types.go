@@ -3,6 +3,7 @@ package fact
 type RowType uint8
 type RowSubjectType uint8
 type RowObjectType uint8
+type RowLiteralType uint8
 
 // NOTE: UPDATE RowMeta AFTER UPDATING THIS
 // TYPE COUNT MUST BE <= 4
@@ -32,3 +33,15 @@ const (
 	RowObjectTypeStringRef
 	RowObjectTypeIDRef
 )
+
+// RowLiteralType is the datatype of RowObjectTypeUInt64 objects, which hold
+// the 8 bytes of integers, booleans, dates and timestamps alike.
+// NOTE: UPDATE RowMeta AFTER UPDATING THIS
+// TYPE COUNT MUST BE <= 256
+const (
+	RowLiteralTypeUInt64 RowLiteralType = iota
+	RowLiteralTypeInt64
+	RowLiteralTypeBool
+	RowLiteralTypeDate
+	RowLiteralTypeTimestamp
+)
@@ -3,7 +3,10 @@ package store
 import (
 	"fmt"
 	"log"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ozansz/semantix/internal/parser"
 	"github.com/ozansz/semantix/pkg/ptrutils"
@@ -11,8 +14,17 @@ import (
 
 type Object struct {
 	StringValue *string
-	FloatValue  *float64
-	Kind        ObjectKind
+	// Lang and Datatype annotate strings with a language tag or a datatype,
+	// at most one of them.
+	Lang       string
+	Datatype   string
+	FloatValue *float64
+	IntValue   *int64
+	FactValue  *parser.Fact
+	BoolValue  *bool
+	// TimeValue holds dates, as midnight UTC of the day, and timestamps.
+	TimeValue *time.Time
+	Kind      ObjectKind
 }
 
 type ObjectKind int
@@ -21,103 +33,595 @@ const (
 	ObjectKindSubject ObjectKind = iota
 	ObjectKindString
 	ObjectKindFloat
+	ObjectKindFact
+	ObjectKindBool
+	ObjectKindInt
+	ObjectKindDate
+	ObjectKindTimestamp
 )
 
 type Query struct {
-	SubjectFilter      *string
-	SubjectFilterQuery *Query
-	PredicateFilter    *string
-	ObjectFilterString *string
-	ObjectFilterFloat  *float64
-	ObjectFilterQuery  *Query
-	LinkedQuery        *Query
+	// Union holds alternative query chains, in which case the pattern itself
+	// is empty.
+	Union []*Query
+	// Exists and NotExists hold query chains that filter rows on whether they
+	// have a solution under the row's bindings, Within a query chain evaluated
+	// in the context of an agent, Filter an expression rows must satisfy, and
+	// Binding an expression whose value is bound in every row. The pattern is
+	// empty if any of them is set.
+	Exists                 *Query
+	NotExists              *Query
+	Within                 *Within
+	Filter                 Expr
+	Binding                *Bind
+	SubjectFilter          *string
+	SubjectFilterNegated   *string
+	SubjectFilterQuery     *Query
+	SubjectVar             *string
+	PredicateFilter        *string
+	PredicatePath          *PredicatePath
+	PredicateFilterNegated *string
+	PredicateVar           *string
+	ObjectFilterString     *string
+	ObjectFilterFloat      *float64
+	// ObjectFilterLiteral holds the integer, boolean, date, timestamp and
+	// annotated string objects of the pattern.
+	ObjectFilterLiteral *Object
+	// ObjectFilterLang matches the strings tagged with the language or one of
+	// its subtags.
+	ObjectFilterLang    *string
+	ObjectFilterNegated *Object
+	ObjectFilterCompare []*Comparison
+	ObjectFilterQuery   *Query
+	ObjectVar           *string
+	LinkedQuery         *Query
+	// Optional patterns keep the rows they fail to extend, leaving their
+	// variables unbound.
+	Optional  bool
+	Modifiers *Modifiers
+}
+
+// Reader answers queries over the facts of a store.
+type Reader interface {
+	Get(*Query) (map[uint32]*parser.Fact, error)
+}
+
+// DefaultGraph is the graph of the facts added without naming one.
+const DefaultGraph = "default"
+
+// GraphReader is a Reader whose facts are partitioned into named graphs.
+type GraphReader interface {
+	Reader
+	// InGraphs returns a reader of the asserted facts of the graphs. Facts
+	// derived by rules are in no graph.
+	InGraphs(graphs []string) Reader
 }
 
 type Store interface {
 	Add(*parser.Fact) error
+	// AddAll adds all of the facts, or none of them if it fails.
+	AddAll([]*parser.Fact) error
 	Get(*Query) (map[uint32]*parser.Fact, error)
+	// Asserted returns a reader of the asserted facts, leaving out the ones
+	// derived by rules.
+	Asserted() Reader
+	// GetByID returns the fact with the given ID, asserted or derived.
+	GetByID(uint32) (*parser.Fact, error)
+	Delete(*parser.Fact) error
+	// Replace replaces the old fact with the new one, along with every fact
+	// nesting the old one, so that nested facts keep referencing it.
+	Replace(old, new *parser.Fact) error
+	AddRule(*Rule) error
+
+	// AddToGraph adds the facts to the graph. A fact can be in several
+	// graphs, the facts added by Add and AddAll are in the DefaultGraph.
+	AddToGraph(graph string, facts []*parser.Fact) error
+	// DropGraph removes the facts of the graph, except the ones that are in
+	// other graphs as well.
+	DropGraph(graph string) error
+	// ReloadGraph replaces the facts of the graph with the given ones.
+	ReloadGraph(graph string, facts []*parser.Fact) error
+	InGraphs(graphs []string) Reader
 
 	Sync() error
 
 	Close() error
 }
 
+// CheckFacts returns an error if any of the facts, or a fact they nest, holds
+// a number that is not finite. Such numbers have no literal form, so stores
+// reject them rather than write facts they could not read back.
+func CheckFacts(facts ...*parser.Fact) error {
+	for _, f := range facts {
+		if n, ok := f.Object.(parser.NumberObject); ok && (math.IsNaN(n.Value) || math.IsInf(n.Value, 0)) {
+			return fmt.Errorf("fact %s: number %v is not finite", f.Pretty(), n.Value)
+		}
+		for _, nested := range []*parser.Fact{f.SubjectFact, f.ObjectFact} {
+			if nested == nil {
+				continue
+			}
+			if err := CheckFacts(nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (o *Object) String() string {
 	switch o.Kind {
 	case ObjectKindSubject:
 		return *o.StringValue
 	case ObjectKindString:
+		switch {
+		case o.Lang != "":
+			return fmt.Sprintf("%q@%s", *o.StringValue, o.Lang)
+		case o.Datatype != "":
+			return fmt.Sprintf("%q^^%s", *o.StringValue, o.Datatype)
+		}
 		return fmt.Sprintf("%q", *o.StringValue)
 	case ObjectKindFloat:
-		return fmt.Sprintf("%f", *o.FloatValue)
+		return parser.FormatNumber(*o.FloatValue)
+	case ObjectKindFact:
+		return o.FactValue.Pretty()
+	case ObjectKindBool:
+		return strconv.FormatBool(*o.BoolValue)
+	case ObjectKindInt:
+		return strconv.FormatInt(*o.IntValue, 10)
+	case ObjectKindDate:
+		return o.TimeValue.Format(parser.DateLayout)
+	case ObjectKindTimestamp:
+		return o.TimeValue.Format(time.RFC3339Nano)
 	}
 	log.Panicf("Unreachable, Object has an unexpected kind: %v", o.Kind)
 	return ""
 }
 
-func QueryFromAST(q *parser.Query) *Query {
-	qq := &Query{}
+// Equal reports whether both objects are of the same kind and hold the same
+// value. Timestamps are equal if they are the same instant, whatever zone they
+// are written in.
+func (o *Object) Equal(other *Object) bool {
+	if o.Kind != other.Kind {
+		return false
+	}
+	switch o.Kind {
+	case ObjectKindSubject:
+		return *o.StringValue == *other.StringValue
+	case ObjectKindString:
+		return *o.StringValue == *other.StringValue && o.Lang == other.Lang && o.Datatype == other.Datatype
+	case ObjectKindFloat:
+		return *o.FloatValue == *other.FloatValue
+	case ObjectKindFact:
+		return o.FactValue.Pretty() == other.FactValue.Pretty()
+	case ObjectKindBool:
+		return *o.BoolValue == *other.BoolValue
+	case ObjectKindInt:
+		return *o.IntValue == *other.IntValue
+	case ObjectKindDate, ObjectKindTimestamp:
+		return o.TimeValue.Equal(*other.TimeValue)
+	}
+	return false
+}
+
+// annotated reports whether the object is a string with a language tag or a
+// datatype.
+func (o *Object) annotated() bool {
+	return o.Lang != "" || o.Datatype != ""
+}
+
+// objectKey identifies the object by its kind and value, for use as a map key.
+func objectKey(o *Object) string {
+	return fmt.Sprintf("%d:%s", o.Kind, o.String())
+}
+
+func ObjectFromSubject(s string) *Object {
+	return &Object{StringValue: ptrutils.Ptr(s), Kind: ObjectKindSubject}
+}
+
+func ObjectFromBool(v bool) *Object {
+	return &Object{BoolValue: ptrutils.Ptr(v), Kind: ObjectKindBool}
+}
+
+func ObjectFromFact(f *parser.Fact) *Object {
+	return &Object{FactValue: f.Copy(), Kind: ObjectKindFact}
+}
+
+func ObjectFromAST(o parser.Object) *Object {
+	switch o.Kind() {
+	case parser.ObjectKindSubject:
+		return ObjectFromSubject(o.InnerValue().(string))
+	case parser.ObjectKindString:
+		s := o.(parser.StringObject)
+		return &Object{StringValue: ptrutils.Ptr(s.Value), Lang: string(s.Lang), Datatype: s.Datatype, Kind: ObjectKindString}
+	case parser.ObjectKindNumber:
+		return &Object{FloatValue: ptrutils.Ptr(o.InnerValue().(float64)), Kind: ObjectKindFloat}
+	case parser.ObjectKindInteger:
+		return intObject(o.InnerValue().(int64))
+	case parser.ObjectKindBool:
+		return ObjectFromBool(o.InnerValue().(bool))
+	case parser.ObjectKindDate:
+		return timeObject(o.InnerValue().(time.Time), ObjectKindDate)
+	case parser.ObjectKindTimestamp:
+		return timeObject(o.InnerValue().(time.Time), ObjectKindTimestamp)
+	}
+	log.Panicf("Unreachable, parser.Object has an unexpected kind: %v", o.Kind())
+	return nil
+}
+
+func QueryFromAST(q *parser.Query) (*Query, error) {
+	var err error
+	qq := &Query{Optional: q.Optional}
+	for _, u := range q.Union {
+		uq, err := QueryFromAST(u)
+		if err != nil {
+			return nil, err
+		}
+		qq.Union = append(qq.Union, uq)
+	}
+	if q.Exists != nil {
+		if qq.Exists, err = QueryFromAST(q.Exists); err != nil {
+			return nil, err
+		}
+	}
+	if q.NotExists != nil {
+		if qq.NotExists, err = QueryFromAST(q.NotExists); err != nil {
+			return nil, err
+		}
+	}
+	if q.Within != nil {
+		if qq.Within, err = WithinFromAST(q.Within); err != nil {
+			return nil, err
+		}
+	}
+	if q.Filter != nil {
+		if qq.Filter, err = ExprFromAST(q.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if q.Bind != nil {
+		if qq.Binding, err = BindFromAST(q.Bind); err != nil {
+			return nil, err
+		}
+	}
+	if q.Optional && (q.Exists != nil || q.NotExists != nil || q.Within != nil || q.Filter != nil || q.Bind != nil) {
+		return nil, fmt.Errorf("only patterns and unions can be optional: %s", q.Pretty())
+	}
+	for _, n := range []*parser.Query{q.SubjectQuery, q.ObjectQuery} {
+		if n != nil && (len(n.Union) > 0 || n.Exists != nil || n.NotExists != nil || n.Within != nil || n.Filter != nil || n.Bind != nil) {
+			return nil, fmt.Errorf("nested facts must be plain patterns: %s", q.Pretty())
+		}
+	}
 	if q.Subject != nil {
 		qq.SubjectFilter = ptrutils.PtrFromPtr(q.Subject)
 	}
+	if q.SubjectVar != nil {
+		qq.SubjectVar = ptrutils.PtrFromPtr(q.SubjectVar)
+	}
+	if q.SubjectNegated != nil {
+		qq.SubjectFilterNegated = ptrutils.PtrFromPtr(q.SubjectNegated)
+	}
 	if q.SubjectQuery != nil {
-		qq.SubjectFilterQuery = QueryFromAST(q.SubjectQuery)
+		if qq.SubjectFilterQuery, err = QueryFromAST(q.SubjectQuery); err != nil {
+			return nil, err
+		}
 	}
 	if q.Predicate != nil {
 		qq.PredicateFilter = ptrutils.PtrFromPtr(q.Predicate)
 	}
+	if q.PredicatePath != nil {
+		if q.SubjectNegated != nil || q.SubjectQuery != nil || q.ObjectNegated != nil || q.ObjectQuery != nil {
+			return nil, fmt.Errorf("predicate paths only connect plain subjects and objects: %s", q.Pretty())
+		}
+		if qq.PredicatePath, err = PathFromAST(q.PredicatePath); err != nil {
+			return nil, err
+		}
+	}
+	if q.PredicateVar != nil {
+		qq.PredicateVar = ptrutils.PtrFromPtr(q.PredicateVar)
+	}
+	if q.PredicateNegated != nil {
+		qq.PredicateFilterNegated = ptrutils.PtrFromPtr(q.PredicateNegated)
+	}
 	if q.Object != nil {
-		if q.Object.IsNumber() {
-			qq.ObjectFilterFloat = ptrutils.Ptr(q.Object.InnerValue().(float64))
-		} else {
-			qq.ObjectFilterString = ptrutils.Ptr(q.Object.InnerValue().(string))
+		switch o := ObjectFromAST(q.Object); {
+		case isStringKind(o.Kind) && !o.annotated():
+			qq.ObjectFilterString = o.StringValue
+		case o.Kind == ObjectKindFloat:
+			qq.ObjectFilterFloat = o.FloatValue
+		default:
+			qq.ObjectFilterLiteral = o
 		}
 	}
+	if q.ObjectLang != nil {
+		qq.ObjectFilterLang = ptrutils.Ptr(string(*q.ObjectLang))
+	}
+	if q.ObjectFilter != nil {
+		if qq.ObjectFilterCompare, err = comparisonsFromAST(q.ObjectFilter); err != nil {
+			return nil, err
+		}
+	}
+	if q.ObjectVar != nil {
+		qq.ObjectVar = ptrutils.PtrFromPtr(q.ObjectVar)
+	}
+	if q.ObjectNegated != nil {
+		qq.ObjectFilterNegated = ObjectFromAST(q.ObjectNegated)
+	}
 	if q.ObjectQuery != nil {
-		qq.ObjectFilterQuery = QueryFromAST(q.ObjectQuery)
+		if qq.ObjectFilterQuery, err = QueryFromAST(q.ObjectQuery); err != nil {
+			return nil, err
+		}
+	}
+	if q.LinkedQuery != nil {
+		if qq.LinkedQuery, err = QueryFromAST(q.LinkedQuery); err != nil {
+			return nil, err
+		}
+	}
+	return qq, nil
+}
+
+func comparisonsFromAST(f *parser.ObjectFilter) ([]*Comparison, error) {
+	if f.Operator == nil {
+		if *f.Low > *f.High {
+			return nil, fmt.Errorf("empty range: %s", f.Pretty())
+		}
+		return []*Comparison{
+			{Operator: OperatorGreaterEqual, Value: &Object{FloatValue: ptrutils.PtrFromPtr(f.Low), Kind: ObjectKindFloat}},
+			{Operator: OperatorLessEqual, Value: &Object{FloatValue: ptrutils.PtrFromPtr(f.High), Kind: ObjectKindFloat}},
+		}, nil
+	}
+	c, err := NewComparison(ComparisonOperator(*f.Operator), ObjectFromAST(f.Value))
+	if err != nil {
+		return nil, err
 	}
-	return qq
+	return []*Comparison{c}, nil
 }
 
+// Matches reports whether the fact matches the query pattern, including the
+// equality of variables that are repeated within the pattern. Linked queries
+// and predicate paths are not considered, they are evaluated by Evaluate.
 func (q *Query) Matches(t *parser.Fact) bool {
-	if q.LinkedQuery != nil {
-		// TODO: Implement linked queries
-		log.Panicf("Linked queries are not supported yet")
+	return q.match(t, Bindings{})
+}
+
+// Bind matches the fact against the query pattern and returns a copy of the
+// given bindings extended with the values of the pattern's variables. It
+// returns false if the fact does not match or contradicts an existing binding.
+func (q *Query) Bind(t *parser.Fact, b Bindings) (Bindings, bool) {
+	nb := b.Copy()
+	if !q.match(t, nb) {
+		return nil, false
 	}
+	return nb, true
+}
+
+func (q *Query) match(t *parser.Fact, b Bindings) bool {
 	if q.SubjectFilter != nil && ((t.Subject != nil && *t.Subject != *q.SubjectFilter) || (t.Subject == nil)) {
 		return false
 	}
-	if q.SubjectFilterQuery != nil && (t.SubjectFact != nil && !q.SubjectFilterQuery.Matches(t.SubjectFact) || t.SubjectFact == nil) {
+	if q.SubjectFilterNegated != nil && t.Subject != nil && *t.Subject == *q.SubjectFilterNegated {
+		return false
+	}
+	if q.SubjectFilterQuery != nil && (t.SubjectFact != nil && !q.SubjectFilterQuery.match(t.SubjectFact, b) || t.SubjectFact == nil) {
+		return false
+	}
+	if q.SubjectVar != nil && !b.bind(*q.SubjectVar, subjectOf(t)) {
 		return false
 	}
 	if q.PredicateFilter != nil && t.Predicate != *q.PredicateFilter {
 		return false
 	}
-	if q.ObjectFilterString != nil && (t.Object != nil && (!t.Object.IsNumber() && t.Object.InnerValue().(string) != *q.ObjectFilterString || t.Object.IsNumber()) || t.Object == nil) {
+	if q.PredicateFilterNegated != nil && t.Predicate == *q.PredicateFilterNegated {
+		return false
+	}
+	if q.PredicateVar != nil && !b.bind(*q.PredicateVar, ObjectFromSubject(t.Predicate)) {
+		return false
+	}
+	return q.matchObject(objectOf(t), b)
+}
+
+// matchObject reports whether the object matches the object term of the
+// query pattern, binding the object variable if any. Numbers match constant
+// numbers of equal value, whether integers or floats, as in filters.
+func (q *Query) matchObject(o *Object, b Bindings) bool {
+	if q.ObjectFilterString != nil && (!isStringKind(o.Kind) || *o.StringValue != *q.ObjectFilterString) {
+		return false
+	}
+	if q.ObjectFilterFloat != nil && !equalValues(o, floatObject(*q.ObjectFilterFloat)) {
+		return false
+	}
+	if q.ObjectFilterLiteral != nil && !equalValues(o, q.ObjectFilterLiteral) {
+		return false
+	}
+	if q.ObjectFilterLang != nil && (o.Kind != ObjectKindString || !parser.LangTag(o.Lang).Matches(parser.LangTag(*q.ObjectFilterLang))) {
 		return false
 	}
-	if q.ObjectFilterFloat != nil && (t.Object != nil && (t.Object.IsNumber() && t.Object.InnerValue().(float64) != *q.ObjectFilterFloat || !t.Object.IsNumber()) || t.Object == nil) {
+	if q.ObjectFilterNegated != nil && equalValues(o, q.ObjectFilterNegated) {
 		return false
 	}
-	if q.ObjectFilterQuery != nil && (t.ObjectFact != nil && !q.ObjectFilterQuery.Matches(t.ObjectFact) || t.ObjectFact == nil) {
+	for _, c := range q.ObjectFilterCompare {
+		if !c.Holds(o) {
+			return false
+		}
+	}
+	if q.ObjectFilterQuery != nil && (o.Kind != ObjectKindFact || !q.ObjectFilterQuery.match(o.FactValue, b)) {
+		return false
+	}
+	if q.ObjectVar != nil && !b.bind(*q.ObjectVar, o) {
 		return false
 	}
 	return true
 }
 
+// positive returns a copy of the query pattern where every negated term is
+// replaced by the term it negates, or nil if the pattern has no negated terms.
+// A fact matching the positive pattern refutes the negation.
+func (q *Query) positive() *Query {
+	var subjectQuery, objectQuery *Query
+	if q.SubjectFilterQuery != nil {
+		subjectQuery = q.SubjectFilterQuery.positive()
+	}
+	if q.ObjectFilterQuery != nil {
+		objectQuery = q.ObjectFilterQuery.positive()
+	}
+	if q.SubjectFilterNegated == nil && q.PredicateFilterNegated == nil && q.ObjectFilterNegated == nil &&
+		subjectQuery == nil && objectQuery == nil {
+		return nil
+	}
+	qq := *q
+	qq.LinkedQuery = nil
+	if q.SubjectFilterNegated != nil {
+		qq.SubjectFilter = q.SubjectFilterNegated
+		qq.SubjectFilterNegated = nil
+	}
+	if q.PredicateFilterNegated != nil {
+		qq.PredicateFilter = q.PredicateFilterNegated
+		qq.PredicateFilterNegated = nil
+	}
+	if q.ObjectFilterNegated != nil {
+		switch q.ObjectFilterNegated.Kind {
+		case ObjectKindFloat:
+			qq.ObjectFilterFloat = q.ObjectFilterNegated.FloatValue
+		case ObjectKindSubject, ObjectKindString:
+			if q.ObjectFilterNegated.annotated() {
+				qq.ObjectFilterLiteral = q.ObjectFilterNegated
+			} else {
+				qq.ObjectFilterString = q.ObjectFilterNegated.StringValue
+			}
+		default:
+			qq.ObjectFilterLiteral = q.ObjectFilterNegated
+		}
+		qq.ObjectFilterNegated = nil
+	}
+	if subjectQuery != nil {
+		qq.SubjectFilterQuery = subjectQuery
+	}
+	if objectQuery != nil {
+		qq.ObjectFilterQuery = objectQuery
+	}
+	return &qq
+}
+
+// Variables returns the returned (?-prefixed) variables of the query in the
+// order they first appear.
+func (q *Query) Variables() []string {
+	vars := []string{}
+	seen := map[string]bool{}
+	q.walkVariables(func(v string) {
+		if IsReturnedVar(v) && !seen[v] {
+			seen[v] = true
+			vars = append(vars, v)
+		}
+	})
+	return vars
+}
+
+func (q *Query) walkVariables(fn func(string)) {
+	q.walkPatternVariables(fn)
+	if q.LinkedQuery != nil {
+		q.LinkedQuery.walkVariables(fn)
+	}
+}
+
+// walkPatternVariables visits the variables of the pattern of q, ignoring its
+// linked queries.
+func (q *Query) walkPatternVariables(fn func(string)) {
+	for _, u := range q.Union {
+		u.walkVariables(fn)
+	}
+	if q.Binding != nil {
+		fn(q.Binding.Variable)
+	}
+	if q.Within != nil {
+		if q.Within.AgentVar != nil {
+			fn(*q.Within.AgentVar)
+		}
+		q.Within.Query.walkVariables(fn)
+	}
+	if q.SubjectVar != nil {
+		fn(*q.SubjectVar)
+	}
+	if q.SubjectFilterQuery != nil {
+		q.SubjectFilterQuery.walkVariables(fn)
+	}
+	if q.PredicateVar != nil {
+		fn(*q.PredicateVar)
+	}
+	if q.ObjectVar != nil {
+		fn(*q.ObjectVar)
+	}
+	if q.ObjectFilterQuery != nil {
+		q.ObjectFilterQuery.walkVariables(fn)
+	}
+}
+
 func (q *Query) Pretty() string {
 	var sb strings.Builder
+	if q.Optional {
+		sb.WriteString("optional ")
+	}
+	if len(q.Union) > 0 {
+		sb.WriteString("{ ")
+		for i, u := range q.Union {
+			if i > 0 {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(u.Pretty())
+		}
+		sb.WriteString(" }")
+	} else if q.Exists != nil {
+		sb.WriteString("exists { ")
+		sb.WriteString(q.Exists.Pretty())
+		sb.WriteString(" }")
+	} else if q.NotExists != nil {
+		sb.WriteString("not exists { ")
+		sb.WriteString(q.NotExists.Pretty())
+		sb.WriteString(" }")
+	} else if q.Within != nil {
+		sb.WriteString(q.Within.Pretty())
+	} else if q.Filter != nil {
+		sb.WriteString("filter ")
+		sb.WriteString(q.Filter.Pretty())
+	} else if q.Binding != nil {
+		sb.WriteString("bind ")
+		sb.WriteString(q.Binding.Pretty())
+	} else {
+		q.prettyPattern(&sb)
+	}
+	if q.LinkedQuery != nil {
+		sb.WriteString(" -> ")
+		sb.WriteString(q.LinkedQuery.Pretty())
+	}
+	if q.Modifiers != nil {
+		sb.WriteRune(' ')
+		sb.WriteString(q.Modifiers.Pretty())
+	}
+	return sb.String()
+}
+
+func (q *Query) prettyPattern(sb *strings.Builder) {
 	sb.WriteRune('(')
 	if q.SubjectFilter != nil {
 		sb.WriteString(*q.SubjectFilter)
+	} else if q.SubjectFilterNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(*q.SubjectFilterNegated)
 	} else if q.SubjectFilterQuery != nil {
 		sb.WriteString(q.SubjectFilterQuery.Pretty())
+	} else if q.SubjectVar != nil {
+		sb.WriteString(*q.SubjectVar)
 	} else {
 		sb.WriteString("*")
 	}
 	sb.WriteString(", ")
 	if q.PredicateFilter != nil {
 		sb.WriteString(*q.PredicateFilter)
+	} else if q.PredicatePath != nil {
+		sb.WriteString(q.PredicatePath.Pretty())
+	} else if q.PredicateFilterNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(*q.PredicateFilterNegated)
+	} else if q.PredicateVar != nil {
+		sb.WriteString(*q.PredicateVar)
 	} else {
 		sb.WriteString("*")
 	}
@@ -125,12 +629,41 @@ func (q *Query) Pretty() string {
 	if q.ObjectFilterString != nil {
 		sb.WriteString(*q.ObjectFilterString)
 	} else if q.ObjectFilterFloat != nil {
-		sb.WriteString(fmt.Sprintf("%f", *q.ObjectFilterFloat))
+		sb.WriteString(parser.FormatNumber(*q.ObjectFilterFloat))
+	} else if q.ObjectFilterLiteral != nil {
+		sb.WriteString(q.ObjectFilterLiteral.String())
+	} else if q.ObjectFilterLang != nil {
+		sb.WriteString("@" + *q.ObjectFilterLang)
+	} else if q.ObjectFilterNegated != nil {
+		sb.WriteRune('~')
+		sb.WriteString(q.ObjectFilterNegated.String())
+	} else if len(q.ObjectFilterCompare) > 0 {
+		for i, c := range q.ObjectFilterCompare {
+			if i > 0 {
+				sb.WriteString(" && ")
+			}
+			sb.WriteString(c.Pretty())
+		}
 	} else if q.ObjectFilterQuery != nil {
 		sb.WriteString(q.ObjectFilterQuery.Pretty())
+	} else if q.ObjectVar != nil {
+		sb.WriteString(*q.ObjectVar)
 	} else {
 		sb.WriteString("*")
 	}
 	sb.WriteRune(')')
-	return sb.String()
+}
+
+func subjectOf(t *parser.Fact) *Object {
+	if t.Subject != nil {
+		return ObjectFromSubject(*t.Subject)
+	}
+	return ObjectFromFact(t.SubjectFact)
+}
+
+func objectOf(t *parser.Fact) *Object {
+	if t.Object != nil {
+		return ObjectFromAST(t.Object)
+	}
+	return ObjectFromFact(t.ObjectFact)
 }
@@ -0,0 +1,384 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+// Template is a fact pattern that is instantiated into facts by substituting
+// its variables with the values bound to them.
+type Template struct {
+	Subject         *string
+	SubjectVar      *string
+	SubjectTemplate *Template
+	Predicate       *string
+	PredicateVar    *string
+	Object          parser.Object
+	ObjectVar       *string
+	ObjectTemplate  *Template
+}
+
+// Rule derives the facts instantiated from Head for every solution of Body.
+type Rule struct {
+	Head *Template
+	Body *Query
+}
+
+// Construct instantiates its templates for every solution of Where. The facts
+// are added to the store if Insert.
+type Construct struct {
+	Insert    bool
+	Templates []*Template
+	Where     *Query
+}
+
+func TemplateFromAST(q *parser.Query) (*Template, error) {
+	if q.Optional || len(q.Union) > 0 || q.Exists != nil || q.NotExists != nil || q.Within != nil || q.Filter != nil || q.Bind != nil {
+		return nil, fmt.Errorf("fact templates must be plain patterns: %s", q.Pretty())
+	}
+	if q.PredicatePath != nil {
+		return nil, fmt.Errorf("predicate paths are not allowed in fact templates: %s", q.Pretty())
+	}
+	if q.SubjectNegated != nil || q.PredicateNegated != nil || q.ObjectNegated != nil {
+		return nil, fmt.Errorf("negated terms are not allowed in fact templates: %s", q.Pretty())
+	}
+	if q.ObjectFilter != nil || q.ObjectLang != nil {
+		return nil, fmt.Errorf("object filters are not allowed in fact templates: %s", q.Pretty())
+	}
+	if q.LinkedQuery != nil {
+		return nil, fmt.Errorf("linked queries are not allowed in fact templates: %s", q.Pretty())
+	}
+	var err error
+	t := &Template{
+		Subject:      ptrutils.PtrFromPtr(q.Subject),
+		SubjectVar:   ptrutils.PtrFromPtr(q.SubjectVar),
+		Predicate:    ptrutils.PtrFromPtr(q.Predicate),
+		PredicateVar: ptrutils.PtrFromPtr(q.PredicateVar),
+		ObjectVar:    ptrutils.PtrFromPtr(q.ObjectVar),
+	}
+	if q.Object != nil {
+		t.Object = q.Object.Copy()
+	}
+	if q.SubjectQuery != nil {
+		if t.SubjectTemplate, err = TemplateFromAST(q.SubjectQuery); err != nil {
+			return nil, err
+		}
+	}
+	if q.ObjectQuery != nil {
+		if t.ObjectTemplate, err = TemplateFromAST(q.ObjectQuery); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func RuleFromAST(r *parser.Rule) (*Rule, error) {
+	head, err := TemplateFromAST(r.Head)
+	if err != nil {
+		return nil, err
+	}
+	body, err := QueryFromAST(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	bound := boundVariables(body)
+	for _, v := range head.Variables() {
+		if !bound[v] {
+			return nil, fmt.Errorf("variable %s of the rule head is not bound by the rule body", v)
+		}
+	}
+	return &Rule{
+		Head: head,
+		Body: body,
+	}, nil
+}
+
+func ConstructFromAST(c *parser.Construct) (*Construct, error) {
+	where, err := QueryFromAST(c.Where)
+	if err != nil {
+		return nil, err
+	}
+	if where.Modifiers, err = ModifiersFromAST(c.Modifiers); err != nil {
+		return nil, err
+	}
+	bound := boundVariables(where)
+	if m := where.Modifiers; m != nil && len(m.Aggregates) > 0 {
+		bound = map[string]bool{}
+		for _, v := range m.Variables() {
+			bound[v] = true
+		}
+	}
+	cc := &Construct{
+		Insert: c.Insert,
+		Where:  where,
+	}
+	for _, t := range c.Templates {
+		tt, err := TemplateFromAST(t)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range tt.Variables() {
+			if !bound[v] {
+				return nil, fmt.Errorf("variable %s of the template %s is not bound by the query", v, tt.Pretty())
+			}
+		}
+		cc.Templates = append(cc.Templates, tt)
+	}
+	return cc, nil
+}
+
+// Facts returns the facts instantiated from the templates for every solution
+// of the query, each once, in the order of the solutions and templates.
+func (c *Construct) Facts(s Reader) ([]*parser.Fact, error) {
+	rows, err := Solve(s, c.Where)
+	if err != nil {
+		return nil, err
+	}
+	facts := []*parser.Fact{}
+	seen := map[string]bool{}
+	for _, r := range rows {
+		for _, t := range c.Templates {
+			f, err := t.Instantiate(r.Bindings)
+			if err != nil {
+				return nil, fmt.Errorf("t.Instantiate(%s): %v", t.Pretty(), err)
+			}
+			if k := f.Pretty(); !seen[k] {
+				seen[k] = true
+				facts = append(facts, f)
+			}
+		}
+	}
+	return facts, nil
+}
+
+func (c *Construct) Pretty() string {
+	var sb strings.Builder
+	if c.Insert {
+		sb.WriteString("insert ")
+	} else {
+		sb.WriteString("construct ")
+	}
+	templates := []string{}
+	for _, t := range c.Templates {
+		templates = append(templates, t.Pretty())
+	}
+	if len(templates) == 1 {
+		sb.WriteString(templates[0])
+	} else {
+		sb.WriteString(fmt.Sprintf("{ %s }", strings.Join(templates, ", ")))
+	}
+	// The modifiers apply to the whole construct, so they follow the query.
+	where := *c.Where
+	where.Modifiers = nil
+	sb.WriteString(" where ")
+	sb.WriteString(where.Pretty())
+	if c.Where.Modifiers != nil {
+		sb.WriteRune(' ')
+		sb.WriteString(c.Where.Modifiers.Pretty())
+	}
+	return sb.String()
+}
+
+// boundVariables returns the variables bound by every solution of the query.
+// Variables of optional patterns may be left unbound, variables of unions are
+// only bound if every alternative binds them, and variables of binds are only
+// bound if the variables of their expression are.
+func boundVariables(q *Query) map[string]bool {
+	bound := map[string]bool{}
+	for curr := q; curr != nil; curr = curr.LinkedQuery {
+		if curr.Optional {
+			continue
+		}
+		if curr.Binding != nil {
+			defined := true
+			curr.Binding.Expr.walkVariables(func(v string) {
+				defined = defined && bound[v]
+			})
+			bound[curr.Binding.Variable] = bound[curr.Binding.Variable] || defined
+			continue
+		}
+		if curr.Within != nil {
+			if v := curr.Within.AgentVar; v != nil {
+				bound[*v] = true
+			}
+			for v := range boundVariables(curr.Within.Query) {
+				bound[v] = true
+			}
+			continue
+		}
+		if len(curr.Union) == 0 {
+			curr.walkPatternVariables(func(v string) {
+				bound[v] = true
+			})
+			continue
+		}
+		common := boundVariables(curr.Union[0])
+		for _, u := range curr.Union[1:] {
+			ub := boundVariables(u)
+			for v := range common {
+				if !ub[v] {
+					delete(common, v)
+				}
+			}
+		}
+		for v := range common {
+			bound[v] = true
+		}
+	}
+	return bound
+}
+
+// Variables returns all variables of the template in the order they first
+// appear.
+func (t *Template) Variables() []string {
+	vars := []string{}
+	seen := map[string]bool{}
+	t.walkVariables(func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			vars = append(vars, v)
+		}
+	})
+	return vars
+}
+
+func (t *Template) walkVariables(fn func(string)) {
+	if t.SubjectVar != nil {
+		fn(*t.SubjectVar)
+	}
+	if t.SubjectTemplate != nil {
+		t.SubjectTemplate.walkVariables(fn)
+	}
+	if t.PredicateVar != nil {
+		fn(*t.PredicateVar)
+	}
+	if t.ObjectVar != nil {
+		fn(*t.ObjectVar)
+	}
+	if t.ObjectTemplate != nil {
+		t.ObjectTemplate.walkVariables(fn)
+	}
+}
+
+// Instantiate returns the fact obtained by substituting the variables of the
+// template with their bindings.
+func (t *Template) Instantiate(b Bindings) (*parser.Fact, error) {
+	f := &parser.Fact{}
+	var err error
+	switch {
+	case t.Subject != nil:
+		f.Subject = ptrutils.PtrFromPtr(t.Subject)
+	case t.SubjectTemplate != nil:
+		if f.SubjectFact, err = t.SubjectTemplate.Instantiate(b); err != nil {
+			return nil, err
+		}
+	case t.SubjectVar != nil:
+		o, ok := b[*t.SubjectVar]
+		if !ok {
+			return nil, fmt.Errorf("variable %s is not bound", *t.SubjectVar)
+		}
+		switch o.Kind {
+		case ObjectKindSubject:
+			f.Subject = ptrutils.PtrFromPtr(o.StringValue)
+		case ObjectKindFact:
+			f.SubjectFact = o.FactValue.Copy()
+		default:
+			return nil, fmt.Errorf("variable %s is bound to %s, which can not be a subject", *t.SubjectVar, o.String())
+		}
+	}
+	switch {
+	case t.Predicate != nil:
+		f.Predicate = *t.Predicate
+	case t.PredicateVar != nil:
+		o, ok := b[*t.PredicateVar]
+		if !ok {
+			return nil, fmt.Errorf("variable %s is not bound", *t.PredicateVar)
+		}
+		if o.Kind != ObjectKindSubject {
+			return nil, fmt.Errorf("variable %s is bound to %s, which can not be a predicate", *t.PredicateVar, o.String())
+		}
+		f.Predicate = *o.StringValue
+	}
+	switch {
+	case t.Object != nil:
+		f.Object = t.Object.Copy()
+	case t.ObjectTemplate != nil:
+		if f.ObjectFact, err = t.ObjectTemplate.Instantiate(b); err != nil {
+			return nil, err
+		}
+	case t.ObjectVar != nil:
+		o, ok := b[*t.ObjectVar]
+		if !ok {
+			return nil, fmt.Errorf("variable %s is not bound", *t.ObjectVar)
+		}
+		switch o.Kind {
+		case ObjectKindFact:
+			f.ObjectFact = o.FactValue.Copy()
+		default:
+			f.Object = o.AST()
+		}
+	}
+	return f, nil
+}
+
+func (t *Template) Pretty() string {
+	var sb strings.Builder
+	sb.WriteRune('(')
+	switch {
+	case t.Subject != nil:
+		sb.WriteString(*t.Subject)
+	case t.SubjectTemplate != nil:
+		sb.WriteString(t.SubjectTemplate.Pretty())
+	case t.SubjectVar != nil:
+		sb.WriteString(*t.SubjectVar)
+	}
+	sb.WriteString(", ")
+	switch {
+	case t.Predicate != nil:
+		sb.WriteString(*t.Predicate)
+	case t.PredicateVar != nil:
+		sb.WriteString(*t.PredicateVar)
+	}
+	sb.WriteString(", ")
+	switch {
+	case t.Object != nil:
+		sb.WriteString(t.Object.String())
+	case t.ObjectTemplate != nil:
+		sb.WriteString(t.ObjectTemplate.Pretty())
+	case t.ObjectVar != nil:
+		sb.WriteString(*t.ObjectVar)
+	}
+	sb.WriteRune(')')
+	return sb.String()
+}
+
+func (r *Rule) Pretty() string {
+	return fmt.Sprintf("%s :- %s", r.Head.Pretty(), r.Body.Pretty())
+}
+
+// AST returns the parser object holding the value. It panics for facts,
+// which are not objects in the parser AST.
+func (o *Object) AST() parser.Object {
+	switch o.Kind {
+	case ObjectKindSubject:
+		return parser.SubjectObject{Value: *o.StringValue}
+	case ObjectKindString:
+		return parser.StringObject{Value: *o.StringValue, Lang: parser.LangTag(o.Lang), Datatype: o.Datatype}
+	case ObjectKindFloat:
+		return parser.NumberObject{Value: *o.FloatValue}
+	case ObjectKindInt:
+		return parser.IntegerObject{Value: *o.IntValue}
+	case ObjectKindBool:
+		return parser.BoolObject{Value: parser.Boolean(*o.BoolValue)}
+	case ObjectKindDate:
+		return parser.DateObject{Value: parser.Date{Time: *o.TimeValue}}
+	case ObjectKindTimestamp:
+		return parser.TimestampObject{Value: *o.TimeValue}
+	}
+	log.Panicf("Unreachable, Object has an unexpected kind: %v", o.Kind)
+	return nil
+}
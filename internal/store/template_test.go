@@ -0,0 +1,156 @@
+package store_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+)
+
+func TestConstruct(t *testing.T) {
+	tests := []struct {
+		desc           string
+		facts          []string
+		construct      string
+		want           []string
+		wantConvertErr bool
+		wantErr        bool
+	}{
+		{
+			desc:      "construct",
+			construct: "construct (?x, is, Engineer) where (?x, knows, CS)",
+			want:      []string{"(Ozan, is, Engineer)", "(Ufuk, is, Engineer)"},
+		},
+		{
+			desc:      "insert",
+			construct: "insert (?x, is, Engineer) where (?x, knows, CS)",
+			want:      []string{"(Ozan, is, Engineer)", "(Ufuk, is, Engineer)"},
+		},
+		{
+			desc:      "multiple and nested templates",
+			construct: "insert { (?x, is, Engineer), (METU, certifies, (?x, knows, !t)) } where (?x, knows, !t) -> (!t, subtopicOf, Science)",
+			want: []string{
+				"(METU, certifies, (Ozan, knows, CS))",
+				"(METU, certifies, (Ufuk, knows, CS))",
+				"(Ozan, is, Engineer)",
+				"(Ufuk, is, Engineer)",
+			},
+		},
+		{
+			desc:      "duplicate facts",
+			construct: "construct (CS, knownBy, Someone) where (?x, knows, CS)",
+			want:      []string{"(CS, knownBy, Someone)"},
+		},
+		{
+			desc:      "computed values and modifiers",
+			construct: "construct (?x, ageInMonths, ?m) where (?x, age, ?a) -> bind ?a * 12 as ?m limit 1",
+			want:      []string{"(Ozan, ageInMonths, 288)"},
+		},
+		{
+			desc:      "aggregates",
+			construct: "construct (CS, knownByCount, ?n) where (?x, knows, CS) aggregate count(?x) as ?n",
+			want:      []string{"(CS, knownByCount, 2)"},
+		},
+		{
+			desc:           "unbound template variables",
+			construct:      "construct (?x, is, ?y) where (?x, knows, CS)",
+			wantConvertErr: true,
+		},
+		{
+			desc:           "optional template variables",
+			construct:      "construct (?x, age, ?a) where (?x, is, Person) -> optional (?x, age, ?a)",
+			wantConvertErr: true,
+		},
+		{
+			desc:      "values that can not be instantiated",
+			construct: "insert (?x, ?n, CS) where (?x, name, ?n)",
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			s := newStore(t, p, tc.facts)
+			exp, err := p.ParseLine(tc.construct)
+			if err != nil {
+				t.Fatalf("failed to parse construct: %v", err)
+			}
+			c, err := store.ConstructFromAST(exp.Construct)
+			if tc.wantConvertErr {
+				if err == nil {
+					t.Fatalf("expected a conversion error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to convert construct: %v", err)
+			}
+			facts, err := c.Facts(s)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to construct facts: %v", err)
+			}
+			got := []string{}
+			for _, f := range facts {
+				got = append(got, f.Pretty())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected facts (-want +got):\n%s", diff)
+			}
+			if !c.Insert {
+				return
+			}
+			if err := s.AddAll(facts); err != nil {
+				t.Fatalf("failed to add facts: %v", err)
+			}
+			all, err := s.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			stored := map[string]bool{}
+			for _, f := range all {
+				stored[f.Pretty()] = true
+			}
+			for _, f := range got {
+				if !stored[f] {
+					t.Errorf("inserted fact %s not found in the store", f)
+				}
+			}
+		})
+	}
+}
+
+func TestConstructPretty(t *testing.T) {
+	tests := []string{
+		"construct (?x, is, Engineer) where (?x, knows, CS)",
+		"insert { (?x, is, Engineer), (METU, certifies, (?x, knows, !t)) } where (?x, knows, !t) -> (!t, subtopicOf, Science)",
+		"construct (?x, is, Engineer) where (?x, knows, CS) order by ?x desc limit 1 offset 2",
+		"construct (?x, is, Engineer) where (?x, knows, CS) -> (?x, age, ?a) order by ?a, ?x limit 3",
+		"construct (CS, knownByCount, ?n) where (?x, knows, CS) aggregate count(?x) as ?n",
+	}
+	for _, construct := range tests {
+		construct := construct
+		t.Run(construct, func(t *testing.T) {
+			exp, err := parser.New().ParseLine(construct)
+			if err != nil {
+				t.Fatalf("failed to parse construct: %v", err)
+			}
+			c, err := store.ConstructFromAST(exp.Construct)
+			if err != nil {
+				t.Fatalf("failed to convert construct: %v", err)
+			}
+			if got := c.Pretty(); got != construct {
+				t.Errorf("c.Pretty() = %s, want %s", got, construct)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ozansz/semantix/internal/parser"
+)
+
+// Update rewrites the facts matched by the first pattern of Match into the
+// facts instantiated from Set with the bindings of the solution.
+type Update struct {
+	Match *Query
+	Set   *Template
+}
+
+// Rewrite replaces the Old fact with the New one.
+type Rewrite struct {
+	Old *parser.Fact
+	New *parser.Fact
+}
+
+func UpdateFromAST(u *parser.Update) (*Update, error) {
+	match, err := QueryFromAST(u.Match)
+	if err != nil {
+		return nil, err
+	}
+	if match.Optional || len(match.Union) > 0 || match.Exists != nil || match.NotExists != nil || match.Within != nil ||
+		match.Filter != nil || match.Binding != nil || match.PredicatePath != nil {
+		return nil, fmt.Errorf("updates must start with a pattern matching the facts to rewrite: %s", match.Pretty())
+	}
+	set, err := TemplateFromAST(u.Set)
+	if err != nil {
+		return nil, err
+	}
+	bound := boundVariables(match)
+	for _, v := range set.Variables() {
+		if !bound[v] {
+			return nil, fmt.Errorf("variable %s of the template %s is not bound by the query", v, set.Pretty())
+		}
+	}
+	return &Update{
+		Match: match,
+		Set:   set,
+	}, nil
+}
+
+// Rewrites returns the rewrites of the facts matched by the first pattern of
+// the query, in the order of the solutions. Facts that would be left as they
+// are are skipped, and a fact that would be rewritten into different facts by
+// different solutions is an error.
+func (u *Update) Rewrites(s Reader) ([]*Rewrite, error) {
+	rows, err := Solve(s, u.Match)
+	if err != nil {
+		return nil, err
+	}
+	rewrites := []*Rewrite{}
+	seen := map[uint32]*Rewrite{}
+	for _, r := range rows {
+		f, err := u.Set.Instantiate(r.Bindings)
+		if err != nil {
+			return nil, fmt.Errorf("u.Set.Instantiate(%s): %v", u.Set.Pretty(), err)
+		}
+		id, old := r.FactIDs[0], r.Facts[0]
+		if rw, ok := seen[id]; ok {
+			if rw.New.Pretty() != f.Pretty() {
+				return nil, fmt.Errorf("fact %s would be rewritten into both %s and %s", old.Pretty(), rw.New.Pretty(), f.Pretty())
+			}
+			continue
+		}
+		rw := &Rewrite{Old: old, New: f}
+		seen[id] = rw
+		if old.Pretty() != f.Pretty() {
+			rewrites = append(rewrites, rw)
+		}
+	}
+	return rewrites, nil
+}
+
+// ApplyRewrites replaces the old facts of the rewrites with the new ones, in
+// order. As every replacement also rewrites the facts nesting the replaced
+// one, the rewrites still to apply are rewritten alike. The rewrites are
+// checked before replacing any of the old ones, so that the store is left
+// untouched if one of them can not be applied.
+func ApplyRewrites(s Store, rewrites []*Rewrite) error {
+	for _, rw := range rewrites {
+		if err := CheckFacts(rw.New); err != nil {
+			return err
+		}
+	}
+	pending := chainRewrites(rewrites)
+	if err := checkRewrites(s, pending); err != nil {
+		return err
+	}
+	for _, rw := range pending {
+		if err := s.Replace(rw.Old, rw.New); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chainRewrites returns the rewrites as they are applied in order: the facts
+// of each one rewritten by the replacements preceding it.
+func chainRewrites(rewrites []*Rewrite) []*Rewrite {
+	pending := make([]*Rewrite, len(rewrites))
+	copy(pending, rewrites)
+	for i, rw := range pending {
+		for j := i + 1; j < len(pending); j++ {
+			next := &Rewrite{Old: pending[j].Old, New: pending[j].New}
+			if f, ok := ReplaceNested(next.Old, rw.Old, rw.New); ok {
+				next.Old = f
+			}
+			if f, ok := ReplaceNested(next.New, rw.Old, rw.New); ok {
+				next.New = f
+			}
+			pending[j] = next
+		}
+	}
+	return pending
+}
+
+// checkRewrites replays the chained rewrites on a copy of the asserted facts
+// of the store. A rewrite conflicts if the fact it replaces is gone by then,
+// or if the fact it replaces it with is already there.
+func checkRewrites(s Store, pending []*Rewrite) error {
+	asserted, err := s.Asserted().Get(&Query{})
+	if err != nil {
+		return err
+	}
+	facts := map[string]*parser.Fact{}
+	for _, f := range asserted {
+		facts[f.Pretty()] = f
+	}
+	for _, rw := range pending {
+		old, new := rw.Old.Pretty(), rw.New.Pretty()
+		if _, ok := facts[old]; !ok {
+			return fmt.Errorf("fact %s can not be rewritten into %s: it is not in the store", old, new)
+		}
+		if _, ok := facts[new]; ok {
+			return fmt.Errorf("fact %s can not be rewritten into %s: it is already in the store", old, new)
+		}
+		delete(facts, old)
+		replaced := map[string]*parser.Fact{new: rw.New}
+		for k, f := range facts {
+			if nf, ok := replaceNested(f, old, rw.New); ok {
+				delete(facts, k)
+				replaced[nf.Pretty()] = nf
+			}
+		}
+		for k, f := range replaced {
+			facts[k] = f
+		}
+	}
+	return nil
+}
+
+// ReplaceNested returns a copy of the fact with the facts nested in it, at any
+// depth, that are equal to old replaced with new. It returns false if the fact
+// does not nest old.
+func ReplaceNested(f, old, new *parser.Fact) (*parser.Fact, bool) {
+	return replaceNested(f, old.Pretty(), new)
+}
+
+func replaceNested(f *parser.Fact, old string, new *parser.Fact) (*parser.Fact, bool) {
+	nested := func(n *parser.Fact) (*parser.Fact, bool) {
+		if n == nil {
+			return nil, false
+		}
+		if n.Pretty() == old {
+			return new.Copy(), true
+		}
+		return replaceNested(n, old, new)
+	}
+	subject, subjectChanged := nested(f.SubjectFact)
+	object, objectChanged := nested(f.ObjectFact)
+	if !subjectChanged && !objectChanged {
+		return f, false
+	}
+	nf := f.Copy()
+	if subjectChanged {
+		nf.SubjectFact = subject
+	}
+	if objectChanged {
+		nf.ObjectFact = object
+	}
+	return nf, true
+}
+
+func (u *Update) Pretty() string {
+	return fmt.Sprintf("update %s set %s", u.Match.Pretty(), u.Set.Pretty())
+}
@@ -0,0 +1,227 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/internal/store"
+)
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		desc           string
+		update         string
+		wantRewrites   int
+		want           []string
+		gone           []string
+		wantConvertErr bool
+		wantErr        bool
+	}{
+		{
+			desc:         "computed objects",
+			update:       "update (?x, age, ?a) -> bind ?a + 1 as ?b set (?x, age, ?b)",
+			wantRewrites: 1,
+			want:         []string{"(Ozan, age, 25)"},
+			gone:         []string{"(Ozan, age, 24)"},
+		},
+		{
+			desc:         "nesting facts",
+			update:       "update (Ozan, knows, CS) set (Ozan, knows, AI)",
+			wantRewrites: 1,
+			want: []string{
+				"(Ozan, knows, AI)",
+				"((Ozan, knows, AI), approvedBy, METU)",
+				"(Ufuk, knows, (Ozan, knows, AI))",
+				"(Ezgi, notKnows, (Ufuk, knows, (Ozan, knows, AI)))",
+			},
+			gone: []string{
+				"(Ozan, knows, CS)",
+				"((Ozan, knows, CS), approvedBy, METU)",
+				"(Ufuk, knows, (Ozan, knows, CS))",
+				"(Ezgi, notKnows, (Ufuk, knows, (Ozan, knows, CS)))",
+			},
+		},
+		{
+			desc:         "renamed predicates of nested facts",
+			update:       "update (?x, knows, ?y) set (?x, isFamiliarWith, ?y)",
+			wantRewrites: 3,
+			want: []string{
+				"(Ozan, isFamiliarWith, CS)",
+				"(Ufuk, isFamiliarWith, CS)",
+				"(Ufuk, isFamiliarWith, (Ozan, isFamiliarWith, CS))",
+				"((Ozan, isFamiliarWith, CS), approvedBy, METU)",
+				"(Ezgi, notKnows, (Ufuk, isFamiliarWith, (Ozan, isFamiliarWith, CS)))",
+			},
+			gone: []string{
+				"(Ozan, knows, CS)",
+				"(Ufuk, knows, CS)",
+				"(Ufuk, knows, (Ozan, knows, CS))",
+			},
+		},
+		{
+			desc:         "rewritten subjects",
+			update:       "update (Ezgi, ?p, ?o) set (EzgiSazak, ?p, ?o)",
+			wantRewrites: 2,
+			want: []string{
+				"(EzgiSazak, thinks, (Ozan, is, Person))",
+				"(EzgiSazak, notKnows, (Ufuk, knows, (Ozan, knows, CS)))",
+			},
+			gone: []string{"(Ezgi, thinks, (Ozan, is, Person))"},
+		},
+		{
+			desc:    "values that can not be subjects",
+			update:  "update (?x, is, Person) -> (?x, name, ?n) set (?n, is, Person)",
+			wantErr: true,
+		},
+		{
+			desc:         "unchanged facts",
+			update:       "update (?x, age, ?a) set (?x, age, ?a)",
+			wantRewrites: 0,
+			want:         []string{"(Ozan, age, 24)"},
+		},
+		{
+			desc:    "ambiguous rewrites",
+			update:  "update (?x, is, Person) -> (?y, is, Person) set (?x, is, ?y)",
+			wantErr: true,
+		},
+		{
+			desc:           "no pattern to rewrite",
+			update:         "update filter true -> (?x, is, Person) set (?x, is, Human)",
+			wantConvertErr: true,
+		},
+		{
+			desc:           "unbound template variables",
+			update:         "update (?x, is, Person) set (?x, is, ?y)",
+			wantConvertErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			s := newStore(t, p, nil)
+			exp, err := p.ParseLine(tc.update)
+			if err != nil {
+				t.Fatalf("failed to parse update: %v", err)
+			}
+			u, err := store.UpdateFromAST(exp.Update)
+			if tc.wantConvertErr {
+				if err == nil {
+					t.Fatalf("expected a conversion error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to convert update: %v", err)
+			}
+			rewrites, err := u.Rewrites(s)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to compute rewrites: %v", err)
+			}
+			if len(rewrites) != tc.wantRewrites {
+				t.Errorf("got %d rewrites, want %d", len(rewrites), tc.wantRewrites)
+			}
+			if err := store.ApplyRewrites(s, rewrites); err != nil {
+				t.Fatalf("failed to apply rewrites: %v", err)
+			}
+			all, err := s.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			stored := map[string]bool{}
+			for _, f := range all {
+				stored[f.Pretty()] = true
+			}
+			for _, f := range tc.want {
+				if !stored[f] {
+					t.Errorf("fact %s not found in the store", f)
+				}
+			}
+			for _, f := range tc.gone {
+				if stored[f] {
+					t.Errorf("fact %s is still in the store", f)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyRewritesConflicts(t *testing.T) {
+	tests := []struct {
+		desc     string
+		rewrites [][2]string
+	}{
+		{
+			desc: "missing fact",
+			rewrites: [][2]string{
+				{"(Ozan, age, 24)", "(Ozan, age, 25)"},
+				{"(Ozan, age, 30)", "(Ozan, age, 31)"},
+			},
+		},
+		{
+			desc: "fact replaced earlier",
+			rewrites: [][2]string{
+				{"(Ozan, age, 24)", "(Ozan, age, 25)"},
+				{"(Ozan, age, 24)", "(Ozan, age, 26)"},
+			},
+		},
+		{
+			desc: "existing fact",
+			rewrites: [][2]string{
+				{"(Ozan, age, 24)", "(Ozan, age, 25)"},
+				{"(Ozan, knows, CS)", "(Ufuk, knows, CS)"},
+			},
+		},
+		{
+			desc: "fact added earlier",
+			rewrites: [][2]string{
+				{"(Ozan, age, 24)", "(Ozan, age, 25)"},
+				{"(Ozan, is, Person)", "(Ozan, age, 25)"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			p := parser.New()
+			s := newStore(t, p, nil)
+			before, err := s.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			rewrites := []*store.Rewrite{}
+			for _, rw := range tc.rewrites {
+				old, err := p.ParseLine(rw[0])
+				if err != nil {
+					t.Fatalf("failed to parse fact: %v", err)
+				}
+				new, err := p.ParseLine(rw[1])
+				if err != nil {
+					t.Fatalf("failed to parse fact: %v", err)
+				}
+				rewrites = append(rewrites, &store.Rewrite{Old: old.Fact, New: new.Fact})
+			}
+			if err := store.ApplyRewrites(s, rewrites); err == nil {
+				t.Fatalf("expected an error, got none")
+			}
+			after, err := s.Get(&store.Query{})
+			if err != nil {
+				t.Fatalf("failed to get facts: %v", err)
+			}
+			if len(after) != len(before) {
+				t.Errorf("got %d facts after the failed rewrites, want %d", len(after), len(before))
+			}
+			for id, f := range before {
+				if _, ok := after[id]; !ok {
+					t.Errorf("fact %s is no longer in the store", f.Pretty())
+				}
+			}
+		})
+	}
+}
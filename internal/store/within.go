@@ -0,0 +1,121 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ozansz/semantix/internal/parser"
+	"github.com/ozansz/semantix/pkg/ptrutils"
+)
+
+// Within evaluates Query in the context of an agent: the facts nested as the
+// objects of the agent's facts with the predicate, e.g. the facts Ezgi
+// thinks. Contexts hold the nested facts only, whether or not they are
+// asserted, so a within clause nested in Query traverses the beliefs about
+// beliefs. If AgentVar is unbound, Query is evaluated in the context of every
+// agent, binding it to the agent.
+type Within struct {
+	Agent     *string
+	AgentVar  *string
+	Predicate string
+	Query     *Query
+}
+
+func WithinFromAST(w *parser.Within) (*Within, error) {
+	q, err := QueryFromAST(w.Query)
+	if err != nil {
+		return nil, err
+	}
+	return &Within{
+		Agent:     ptrutils.PtrFromPtr(w.Agent),
+		AgentVar:  ptrutils.PtrFromPtr(w.AgentVar),
+		Predicate: w.Predicate,
+		Query:     q,
+	}, nil
+}
+
+// contextReader reads the facts of a context.
+type contextReader struct {
+	facts map[uint32]*parser.Fact
+}
+
+func (r *contextReader) Get(q *Query) (map[uint32]*parser.Fact, error) {
+	trs := map[uint32]*parser.Fact{}
+	for id, t := range r.facts {
+		if q.Matches(t) {
+			trs[id] = t.Copy()
+		}
+	}
+	return trs, nil
+}
+
+// contexts returns the contexts of the agents that hold facts under the
+// predicate, keyed by agent. If agent is not nil, only its context is
+// returned.
+func (w *Within) contexts(s Reader, agent *string) (map[string]*contextReader, error) {
+	facts, err := s.Get(&Query{SubjectFilter: agent, PredicateFilter: ptrutils.Ptr(w.Predicate)})
+	if err != nil {
+		return nil, err
+	}
+	contexts := map[string]*contextReader{}
+	for _, t := range facts {
+		if t.Subject == nil || t.ObjectFact == nil {
+			continue
+		}
+		c, ok := contexts[*t.Subject]
+		if !ok {
+			c = &contextReader{facts: map[uint32]*parser.Fact{}}
+			contexts[*t.Subject] = c
+		}
+		c.facts[FactHash(t.ObjectFact)] = t.ObjectFact
+	}
+	return contexts, nil
+}
+
+// joinWithin extends every row with the solutions of the query in the
+// contexts of the agents the row agrees with, in the order of the agents.
+func joinWithin(s Reader, rows []*Row, w *Within) ([]*Row, error) {
+	joined := []*Row{}
+	for _, r := range rows {
+		agent := w.Agent
+		if w.AgentVar != nil {
+			if a, ok := r.Bindings[*w.AgentVar]; ok {
+				if a.Kind != ObjectKindSubject {
+					continue
+				}
+				agent = a.StringValue
+			}
+		}
+		contexts, err := w.contexts(s, agent)
+		if err != nil {
+			return nil, err
+		}
+		agents := make([]string, 0, len(contexts))
+		for a := range contexts {
+			agents = append(agents, a)
+		}
+		sort.Strings(agents)
+		for _, a := range agents {
+			row := r
+			if w.AgentVar != nil {
+				b := r.Bindings.Copy()
+				b.bind(*w.AgentVar, ObjectFromSubject(a))
+				row = &Row{Bindings: b, FactIDs: r.FactIDs, Facts: r.Facts}
+			}
+			rs, err := joinChain(contexts[a], []*Row{row}, w.Query)
+			if err != nil {
+				return nil, err
+			}
+			joined = append(joined, rs...)
+		}
+	}
+	return joined, nil
+}
+
+func (w *Within) Pretty() string {
+	agent := w.Agent
+	if agent == nil {
+		agent = w.AgentVar
+	}
+	return fmt.Sprintf("within (%s, %s) { %s }", *agent, w.Predicate, w.Query.Pretty())
+}